@@ -13,6 +13,7 @@ import (
 	"github.com/AsenHu/mewlink/internal/config"
 	"github.com/AsenHu/mewlink/internal/database"
 	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"maunium.net/go/mautrix"
@@ -91,11 +92,17 @@ func main() {
 
 	// 准备 Worker
 	worker := &worker.Worker{
-		DataBase:  db,
-		Config:    cfg,
-		WaitGroup: &wg,
-		Context:   ctx,
-		StopProc:  errCancel,
+		DataBase:    db,
+		Config:      cfg,
+		WaitGroup:   &wg,
+		Context:     ctx,
+		SyncContext: syncCtx,
+		StopProc:    errCancel,
+	}
+	// Worker 遇到错误的时候，先在管理房间里提醒一下
+	worker.StopProc = func() {
+		misc.Alert(ctx, worker, "A worker hit a fatal error, MewLink is stopping, please check the logs")
+		errCancel()
 	}
 
 	// 准备 Matrix 客户端
@@ -119,9 +126,14 @@ func main() {
 				errCancel()
 				return
 			}
-			cfg.Save()
 		}
 
+		// 准备管理房间
+		if err := misc.EnsureManagementRoom(ctx, worker); err != nil {
+			log.Warn().Err(err).Msg("Failed to prepare management room")
+		}
+		misc.Alert(ctx, worker, "MewLink started")
+
 		for {
 			if err := worker.Matrix.SyncWithContext(syncCtx); err != nil {
 				if errors.Is(err, context.Canceled) {
@@ -135,7 +147,7 @@ func main() {
 						errCancel()
 						return
 					}
-					cfg.Save()
+					misc.Alert(ctx, worker, "Matrix token expired, relogged in")
 					continue
 				}
 				if errors.Is(err, mautrix.MInvalidParam) {
@@ -145,7 +157,7 @@ func main() {
 						errCancel()
 						return
 					}
-					cfg.Save()
+					misc.Alert(ctx, worker, "Matrix username format error, relogged in")
 					continue
 				}
 				log.Error().Err(err).Msg("Sync failed")
@@ -219,6 +231,12 @@ func main() {
 		syncCancel()
 	}
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		misc.Alert(ctx, worker, "MewLink is stopping")
+	}()
+
 	// 处理 goroutine 死活不退出的情况
 	go func() {
 		// 等待 1 分钟
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"github.com/AsenHu/mewlink/internal/config"
+	"github.com/AsenHu/mewlink/internal/outbox"
 	"github.com/AsenHu/mewlink/internal/worker"
 	"github.com/AsenHu/mewlink/internal/worker/matrix"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
 	"github.com/AsenHu/mewlink/internal/worker/telegram"
 	"github.com/go-telegram/bot"
 	"github.com/rs/zerolog/log"
@@ -14,7 +17,8 @@ import (
 func setMatrixClient(w *worker.Worker) {
 	// 创建 Matrix 客户端
 	var err error
-	w.Matrix, err = mautrix.NewClient(w.Config.Content.Matrix.BaseURL, id.UserID(w.Config.Content.Matrix.Username), w.Config.Content.Matrix.Token)
+	cfg := w.Config.Get().Matrix
+	w.Matrix, err = mautrix.NewClient(cfg.BaseURL, id.UserID(cfg.Username), cfg.Token)
 	if err != nil {
 		log.Err(err).Msg("Failed to create Matrix client")
 		w.StopProc()
@@ -24,6 +28,17 @@ func setMatrixClient(w *worker.Worker) {
 	// 设置回调函数
 	syncer := mautrix.NewDefaultSyncer()
 	syncer.OnEventType(event.EventMessage, matrix.MatrixWorker{Worker: w}.FromMatrix)
+	syncer.OnEventType(event.EventSticker, matrix.MatrixWorker{Worker: w}.FromMatrix)
+	syncer.OnEventType(event.EventReaction, matrix.MatrixWorker{Worker: w}.FromMatrixReaction)
+	syncer.OnEventType(event.EventUnstablePollStart, matrix.MatrixWorker{Worker: w}.FromMatrixPoll)
+	syncer.OnEventType(event.EventUnstablePollResponse, matrix.MatrixWorker{Worker: w}.FromMatrixPoll)
+	syncer.OnEventType(misc.EventUnstablePollEnd, matrix.MatrixWorker{Worker: w}.FromMatrixPoll)
+	syncer.OnEventType(event.EphemeralEventTyping, matrix.MatrixWorker{Worker: w}.FromMatrixTyping)
+	syncer.OnEventType(event.EphemeralEventReceipt, matrix.MatrixWorker{Worker: w}.FromMatrixReceipt)
+	syncer.OnEventType(event.StateMember, matrix.MatrixWorker{Worker: w}.FromMatrixState)
+	syncer.OnEventType(event.StateTombstone, matrix.MatrixWorker{Worker: w}.FromMatrixState)
+	syncer.OnEventType(event.StateRoomName, matrix.MatrixWorker{Worker: w}.FromMatrixState)
+	syncer.OnEventType(event.StateRoomAvatar, matrix.MatrixWorker{Worker: w}.FromMatrixState)
 	w.Matrix.Syncer = syncer
 }
 
@@ -36,12 +51,14 @@ func setTelegramClient(w *worker.Worker) {
 
 	// 创建 Telegram 客户端
 	var err error
-	w.Telegram, err = bot.New(w.Config.Content.Telegram.Token, opts...)
+	w.Telegram, err = bot.New(w.Config.Get().Telegram.Token, opts...)
 	if err != nil {
 		log.Error().Err(err)
 		w.StopProc()
 		return
 	}
+	w.Outbox = outbox.New(w.Telegram)
+	w.ProcApproved = telegram.TelegramWorker{Worker: w}.ProcApproved
 }
 
 func checkTelegramClient(w *worker.Worker) {
@@ -76,15 +93,16 @@ func checkTelegramClient(w *worker.Worker) {
 
 func matrixLogin(w *worker.Worker) (err error) {
 	// 开始登陆
+	cfg := w.Config.Get().Matrix
 	_, err = w.Matrix.Login(w.Context, &mautrix.ReqLogin{
 		Type: "m.login.password",
 		Identifier: mautrix.UserIdentifier{
 			Type: "m.id.user",
-			User: w.Config.Content.Matrix.Username,
+			User: cfg.Username,
 		},
-		Password:                 w.Config.Content.Matrix.Password,
-		Token:                    w.Config.Content.Matrix.Token,
-		DeviceID:                 w.Config.Content.Matrix.DeviceID,
+		Password:                 cfg.Password,
+		Token:                    cfg.Token,
+		DeviceID:                 cfg.DeviceID,
 		InitialDeviceDisplayName: "MewLink",
 
 		StoreCredentials:   true,
@@ -94,9 +112,12 @@ func matrixLogin(w *worker.Worker) (err error) {
 		return
 	}
 
-	w.Config.Content.Matrix.BaseURL = w.Matrix.HomeserverURL.String()
-	w.Config.Content.Matrix.Username = w.Matrix.UserID.String()
-	w.Config.Content.Matrix.DeviceID = w.Matrix.DeviceID
-	w.Config.Content.Matrix.Token = w.Matrix.AccessToken
-	return
+	// 重新登陆的时候 Worker 已经在运行了，保存新的登陆信息
+	return w.Config.Update(func(content *config.Content) error {
+		content.Matrix.BaseURL = w.Matrix.HomeserverURL.String()
+		content.Matrix.Username = w.Matrix.UserID.String()
+		content.Matrix.DeviceID = w.Matrix.DeviceID
+		content.Matrix.Token = w.Matrix.AccessToken
+		return nil
+	})
 }
@@ -3,14 +3,17 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"sync"
 
 	"github.com/rs/zerolog"
 	"maunium.net/go/mautrix/id"
 )
 
 type Config struct {
-	Path    string
+	Path string
+	// 启动之后其他 goroutine 可能正在修改配置，要通过 Get 和 Update 读写
 	Content Content
+	mutex   sync.RWMutex
 }
 
 type Content struct {
@@ -19,7 +22,9 @@ type Content struct {
 	Matrix     Matrix        `json:"matrix"`
 	Telegram   Telegram      `json:"telegram"`
 	DataBase   string        `json:"databasePath"`
-	Version    uint8         `json:"version"`
+	// Telegram 消息和 Matrix 事件、投票的对应关系保存的天数，0 代表一直保存
+	MessageRetention int64 `json:"messageRetention"`
+	Version          uint8 `json:"version"`
 }
 
 type Matrix struct {
@@ -29,11 +34,64 @@ type Matrix struct {
 	DeviceID    id.DeviceID `json:"deviceID"`
 	Token       string      `json:"token"`
 	AsyncUpload bool        `json:"asyncUpload"`
+	LeavePolicy string      `json:"leavePolicy"`
+	// 用来管理 MewLink 的房间，为空的时候会自动创建
+	ManagementRoom id.RoomID `json:"managementRoom"`
+	// 不转发 m.notice，包括被服务的用户和房间里的机器人或者集成发送的，关闭的时候其他成员只有 m.notice 会转发
+	SuppressNotices bool `json:"suppressNotices"`
+	// 发送了不能转发的消息时，在房间里的提示，{type} 会被替换成消息类型，为空的时候不提示
+	UnsupportedNotice string `json:"unsupportedNotice"`
+	// 连续发送的图片在这个时间内（毫秒）会被合并成一个相册发送
+	ImageBatchWindow int64 `json:"imageBatchWindow"`
+	// 已读回执转发成联系人消息上的 👀，可以在每个房间里单独设置
+	ReadReceipts bool `json:"readReceipts"`
+	// Bot 在被服务的用户的消息上发送已读回执的方式
+	BotReceipts string `json:"botReceipts"`
 }
 
+// 被服务的用户离开桥接房间之后的处理方式
+
+const (
+	LeavePolicyDormant  = "dormant"  // 等联系人发来下一条消息的时候重新创建房间
+	LeavePolicyReinvite = "reinvite" // 立即重新邀请被服务的用户
+	LeavePolicyArchive  = "archive"  // 保留联系人，但不再转发消息
+	LeavePolicyDrop     = "drop"     // 删除联系人，联系人需要重新发送 /start
+)
+
+const (
+	BotReceiptsDelivered = "delivered" // 消息送达 Telegram 之后发送已读回执
+	BotReceiptsNone      = "none"      // 不发送已读回执
+)
+
 type Telegram struct {
 	Token   string  `json:"token"`
 	Webhook Webhook `json:"webhook"`
+	// 同步联系人资料（名字和头像）的最小间隔，单位是秒
+	ProfileSyncInterval int64 `json:"profileSyncInterval"`
+	// 新的联系人需要在管理房间里审批之后才会创建房间
+	ApprovalMode bool `json:"approvalMode"`
+	// 审批被拒绝的时候回复给联系人的消息
+	DenyReply string `json:"denyReply"`
+	// 允许列表和屏蔽列表，条目可以是用户 ID 或者 @username
+	// 这里的列表只能通过修改配置文件修改，在管理房间里添加的条目保存在数据库里
+	AllowList []string `json:"allowList"`
+	DenyList  []string `json:"denyList"`
+	// 只有允许列表里的用户可以使用 Bot
+	AllowListOnly bool `json:"allowListOnly"`
+	// 被屏蔽的用户发来消息时的自动回复，为空的时候不回复
+	BlockReply string `json:"blockReply"`
+	// 每个联系人的限流，每分钟最多 RateLimit 条消息，最多可以连续发送 RateLimitBurst 条，0 代表不限流
+	RateLimit      int64 `json:"rateLimit"`
+	RateLimitBurst int64 `json:"rateLimitBurst"`
+	// 联系人在 10 分钟内触发 MuteAfter 次限流之后会被禁言 MuteDuration 秒，0 代表不禁言
+	MuteAfter    int64 `json:"muteAfter"`
+	MuteDuration int64 `json:"muteDuration"`
+	// 超过这个长度（UTF-16 字符）的消息会以 .txt 文件的形式发送，而不是拆分成多条消息，0 代表总是拆分
+	DocumentThreshold int64 `json:"documentThreshold"`
+	// 联系人发送了不能转发的消息时的回复，key 是联系人的语言代码，找不到的时候使用 default，为空的时候不回复
+	UnsupportedReply map[string]string `json:"unsupportedReply"`
+	// 等待相册里其他消息的时间（毫秒），这个时间内没有新的消息就转发整个相册
+	AlbumWindow int64 `json:"albumWindow"`
 }
 
 type Webhook struct {
@@ -54,14 +112,33 @@ func NewConfig(path string) *Config {
 				Password:    "password",
 				DeviceID:    "MEWLINK",
 				AsyncUpload: true,
+				LeavePolicy: LeavePolicyDormant,
+				UnsupportedNotice: "This message ({type}) can not be delivered to Telegram. " +
+					"Supported: text, emotes, notices, images, locations, vCard files and polls.",
+				ImageBatchWindow: 1500,
+				BotReceipts:      BotReceiptsDelivered,
 			},
 			Telegram: Telegram{
 				Webhook: Webhook{
 					Enable: false,
 				},
+				ProfileSyncInterval: 3600,
+				DenyReply:           "Sorry, your request to chat has been declined.",
+				RateLimit:           20,
+				RateLimitBurst:      10,
+				MuteAfter:           3,
+				MuteDuration:        600,
+				DocumentThreshold:   16384,
+				UnsupportedReply: map[string]string{
+					"default": "Sorry, this kind of message can not be delivered. " +
+						"You can send text, photos, videos, locations, venues, contacts and polls.",
+					"zh": "抱歉，这种消息无法送达。目前支持文字、图片、视频、位置、地点、联系人和投票。",
+				},
+				AlbumWindow: 1500,
 			},
-			DataBase: "mewlink.db",
-			Version:  1,
+			DataBase:         "mewlink.db",
+			MessageRetention: 90,
+			Version:          1,
 		},
 	}
 }
@@ -77,6 +154,35 @@ func (c *Config) Load() (err error) {
 }
 
 func (c *Config) Save() (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.save()
+}
+
+// 在运行的时候读取配置，返回的是一份副本
+// 副本和配置共用列表和 map，所以修改配置的时候不能原地修改它们，要换成新的
+
+func (c *Config) Get() Content {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.Content
+}
+
+// 在运行的时候修改配置，并保存到文件
+// 修改的是一份副本，出错的时候配置不会改变
+
+func (c *Config) Update(update func(content *Content) error) (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	content := c.Content
+	if err = update(&content); err != nil {
+		return
+	}
+	c.Content = content
+	return c.save()
+}
+
+func (c *Config) save() (err error) {
 	// 序列化文件
 	buffer, err := json.MarshalIndent(c.Content, "", "  ")
 	if err != nil {
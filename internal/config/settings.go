@@ -0,0 +1,303 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 可以在管理房间里修改的全局设置
+
+type Setting struct {
+	Name string
+	Desc string
+	Get  func(c *Content) string
+	Set  func(c *Content, value string) error
+}
+
+var Settings = []Setting{
+	{
+		Name: "leavePolicy",
+		Desc: "What to do when you leave a bridged room: dormant, reinvite, archive or drop",
+		Get:  func(c *Content) string { return c.Matrix.LeavePolicy },
+		Set: func(c *Content, value string) error {
+			switch value {
+			case LeavePolicyDormant, LeavePolicyReinvite, LeavePolicyArchive, LeavePolicyDrop:
+				c.Matrix.LeavePolicy = value
+				return nil
+			}
+			return fmt.Errorf("unknown leave policy: %s", value)
+		},
+	},
+	{
+		Name: "profileSyncInterval",
+		Desc: "Minimum seconds between two profile syncs of a contact",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Telegram.ProfileSyncInterval, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.ProfileSyncInterval = n
+			return nil
+		},
+	},
+	{
+		Name: "approvalMode",
+		Desc: "New contacts need to be approved before a room is created: true or false",
+		Get:  func(c *Content) string { return strconv.FormatBool(c.Telegram.ApprovalMode) },
+		Set: func(c *Content, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.ApprovalMode = b
+			return nil
+		},
+	},
+	{
+		Name: "denyReply",
+		Desc: "Reply sent to contacts whose request was denied",
+		Get:  func(c *Content) string { return c.Telegram.DenyReply },
+		Set: func(c *Content, value string) error {
+			c.Telegram.DenyReply = value
+			return nil
+		},
+	},
+	{
+		Name: "allowListOnly",
+		Desc: "Only users in the allow list can use the bot: true or false",
+		Get:  func(c *Content) string { return strconv.FormatBool(c.Telegram.AllowListOnly) },
+		Set: func(c *Content, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.AllowListOnly = b
+			return nil
+		},
+	},
+	{
+		Name: "blockReply",
+		Desc: "Reply sent to blocked users, \"none\" to stay silent",
+		Get:  func(c *Content) string { return c.Telegram.BlockReply },
+		Set: func(c *Content, value string) error {
+			if value == "none" {
+				value = ""
+			}
+			c.Telegram.BlockReply = value
+			return nil
+		},
+	},
+	{
+		Name: "rateLimit",
+		Desc: "Maximum messages per minute from one contact, 0 to disable",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Telegram.RateLimit, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.RateLimit = n
+			return nil
+		},
+	},
+	{
+		Name: "rateLimitBurst",
+		Desc: "Maximum messages a contact can send in a row",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Telegram.RateLimitBurst, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.RateLimitBurst = n
+			return nil
+		},
+	},
+	{
+		Name: "muteAfter",
+		Desc: "Mute a contact after this many floods in 10 minutes, 0 to disable",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Telegram.MuteAfter, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.MuteAfter = n
+			return nil
+		},
+	},
+	{
+		Name: "muteDuration",
+		Desc: "Seconds a flooding contact stays muted",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Telegram.MuteDuration, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.MuteDuration = n
+			return nil
+		},
+	},
+	{
+		Name: "documentThreshold",
+		Desc: "Messages longer than this are sent to Telegram as a .txt file, 0 to always split",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Telegram.DocumentThreshold, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.DocumentThreshold = n
+			return nil
+		},
+	},
+	{
+		Name: "messageRetention",
+		Desc: "Days to remember which Telegram message or poll belongs to which Matrix event, 0 to keep forever",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.MessageRetention, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.MessageRetention = n
+			return nil
+		},
+	},
+	{
+		Name: "suppressNotices",
+		Desc: "Do not forward m.notice messages to Telegram, including those from bots and integrations in the room: true or false",
+		Get:  func(c *Content) string { return strconv.FormatBool(c.Matrix.SuppressNotices) },
+		Set: func(c *Content, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			c.Matrix.SuppressNotices = b
+			return nil
+		},
+	},
+	{
+		Name: "unsupportedNotice",
+		Desc: "Notice shown when you send something that can not be bridged, {type} is the message type, \"none\" to stay silent",
+		Get:  func(c *Content) string { return c.Matrix.UnsupportedNotice },
+		Set: func(c *Content, value string) error {
+			if value == "none" {
+				value = ""
+			}
+			c.Matrix.UnsupportedNotice = value
+			return nil
+		},
+	},
+	{
+		Name: "unsupportedReply",
+		Desc: "Reply to contacts who send something that can not be bridged: <language|default> <text>, \"none\" as text to remove",
+		Get: func(c *Content) string {
+			languages := make([]string, 0, len(c.Telegram.UnsupportedReply))
+			for language := range c.Telegram.UnsupportedReply {
+				languages = append(languages, language)
+			}
+			sort.Strings(languages)
+			replies := make([]string, 0, len(languages))
+			for _, language := range languages {
+				replies = append(replies, language+": "+c.Telegram.UnsupportedReply[language])
+			}
+			return strings.Join(replies, " | ")
+		},
+		Set: func(c *Content, value string) error {
+			language, text, found := strings.Cut(value, " ")
+			text = strings.TrimSpace(text)
+			if !found || text == "" {
+				return fmt.Errorf("usage: <language|default> <text>")
+			}
+			// 其他 goroutine 拿到的配置副本里还是原来的 map，复制一份再修改
+			replies := make(map[string]string, len(c.Telegram.UnsupportedReply)+1)
+			for k, v := range c.Telegram.UnsupportedReply {
+				replies[k] = v
+			}
+			if text == "none" {
+				delete(replies, strings.ToLower(language))
+			} else {
+				replies[strings.ToLower(language)] = text
+			}
+			c.Telegram.UnsupportedReply = replies
+			return nil
+		},
+	},
+	{
+		Name: "albumWindow",
+		Desc: "Milliseconds to wait for the rest of a Telegram album before bridging it",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Telegram.AlbumWindow, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Telegram.AlbumWindow = n
+			return nil
+		},
+	},
+	{
+		Name: "imageBatchWindow",
+		Desc: "Milliseconds to wait for more images from Matrix before sending them to Telegram as one album",
+		Get:  func(c *Content) string { return strconv.FormatInt(c.Matrix.ImageBatchWindow, 10) },
+		Set: func(c *Content, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+			c.Matrix.ImageBatchWindow = n
+			return nil
+		},
+	},
+	{
+		Name: "readReceipts",
+		Desc: "Mark messages you have read with 👀 on Telegram, unless changed in the room: true or false",
+		Get:  func(c *Content) string { return strconv.FormatBool(c.Matrix.ReadReceipts) },
+		Set: func(c *Content, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			c.Matrix.ReadReceipts = b
+			return nil
+		},
+	},
+	{
+		Name: "botReceipts",
+		Desc: "When the bot marks your messages as read: delivered or none, messages that failed get a ⚠️ either way",
+		Get:  func(c *Content) string { return c.Matrix.BotReceipts },
+		Set: func(c *Content, value string) error {
+			switch value {
+			case BotReceiptsDelivered, BotReceiptsNone:
+				c.Matrix.BotReceipts = value
+				return nil
+			}
+			return fmt.Errorf("unknown bot receipts mode: %s", value)
+		},
+	},
+}
+
+func FindSetting(name string) (setting Setting, ok bool) {
+	for _, setting = range Settings {
+		if setting.Name == name {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+func parseNonNegative(value string) (n int64, err error) {
+	n, err = strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return
+	}
+	if n < 0 {
+		err = fmt.Errorf("value must not be negative: %d", n)
+	}
+	return
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.etcd.io/bbolt"
+)
+
+// 允许列表和屏蔽列表
+// 列表里的条目可以是 Telegram 的用户 ID，也可以是 @username
+// 每次收到消息都要检查，所以启动的时候会把列表读到内存里，修改的时候同时修改内存和数据库
+
+type AccessKind uint8
+
+const (
+	AccessAllow AccessKind = 0
+	AccessDeny  AccessKind = 1
+)
+
+type AccessList struct {
+	entries Bucket
+	mutex   sync.RWMutex
+	lists   map[AccessKind]map[string]struct{}
+}
+
+func newAccessList(db *bbolt.DB) (al *AccessList, err error) {
+	al = &AccessList{
+		entries: Bucket{
+			database: db,
+			bucket:   []byte{bucketAccessListEntries},
+			keyLen:   1,
+		},
+		lists: map[AccessKind]map[string]struct{}{
+			AccessAllow: {},
+			AccessDeny:  {},
+		},
+	}
+
+	// 检查 bucket 是否存在
+	exi, err := al.entries.Exists()
+	if err != nil {
+		return
+	}
+	// 如果不存在则创建
+	if !exi {
+		err = al.entries.Create()
+		return
+	}
+
+	// 读取列表，key 的第一个字节是列表的种类，后面是条目
+	err = db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(al.entries.bucket).ForEach(func(k, _ []byte) error {
+			if len(k) < 2 {
+				return nil
+			}
+			if list, ok := al.lists[AccessKind(k[0])]; ok {
+				list[string(k[1:])] = struct{}{}
+			}
+			return nil
+		})
+	})
+	return
+}
+
+// 整理条目，用户 ID 保持不变，用户名去掉 @ 并转换成小写
+
+func NormalizeAccessEntry(entry string) (normalized string, err error) {
+	entry = strings.TrimSpace(entry)
+	if _, err := strconv.ParseInt(entry, 10, 64); err == nil {
+		return entry, nil
+	}
+	username := strings.ToLower(strings.TrimPrefix(entry, "@"))
+	if username == "" || strings.ContainsAny(username, " @") {
+		return "", fmt.Errorf("invalid user ID or username: %s", entry)
+	}
+	return "@" + username, nil
+}
+
+func accessKey(kind AccessKind, entry string) []byte {
+	return append([]byte{byte(kind)}, entry...)
+}
+
+func (al *AccessList) Add(kind AccessKind, entry string) (err error) {
+	entry, err = NormalizeAccessEntry(entry)
+	if err != nil {
+		return
+	}
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	if err = al.entries.Put(accessKey(kind, entry), []byte{}); err != nil {
+		return
+	}
+	al.lists[kind][entry] = struct{}{}
+	return
+}
+
+func (al *AccessList) Remove(kind AccessKind, entry string) (removed bool, err error) {
+	entry, err = NormalizeAccessEntry(entry)
+	if err != nil {
+		return
+	}
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	if _, removed = al.lists[kind][entry]; !removed {
+		return
+	}
+	if err = al.entries.Delete(accessKey(kind, entry)); err != nil {
+		return
+	}
+	delete(al.lists[kind], entry)
+	return
+}
+
+// 检查用户是否在列表里，只读内存
+
+func (al *AccessList) Contains(kind AccessKind, userID int64, username string) bool {
+	al.mutex.RLock()
+	defer al.mutex.RUnlock()
+	if _, ok := al.lists[kind][strconv.FormatInt(userID, 10)]; ok {
+		return true
+	}
+	if username == "" {
+		return false
+	}
+	_, ok := al.lists[kind]["@"+strings.ToLower(username)]
+	return ok
+}
+
+func (al *AccessList) List(kind AccessKind) (entries []string) {
+	al.mutex.RLock()
+	defer al.mutex.RUnlock()
+	for entry := range al.lists[kind] {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return
+}
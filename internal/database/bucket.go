@@ -13,6 +13,12 @@ const (
 	bucketRoomListRoomInfo    uint8 = 1
 	bucketRoomListChatIDIndex uint8 = 2
 	bucketRoomListRoomIDIndex uint8 = 3
+	bucketPendingListPending  uint8 = 4
+	bucketAccessListEntries   uint8 = 5
+	bucketMessageListTelegram uint8 = 6
+	bucketMessageListMatrix   uint8 = 7
+	bucketPollListPolls       uint8 = 8
+	bucketPollListEvents      uint8 = 9
 )
 
 // 对于每一个 bucket，都应该有一个对应的结构体
@@ -133,3 +139,13 @@ func (b *Bucket) FindUnusedKey() (key []byte, lock *sync.RWMutex, err error) {
 	// 这里返回了 key 和 lock，上层函数处理完 value 后应该释放 lock
 	return
 }
+
+// Bucket 应该能够统计自己有多少个 key
+
+func (b *Bucket) Count() (count int, err error) {
+	err = b.database.View(func(tx *bbolt.Tx) error {
+		count = tx.Bucket(b.bucket).Stats().KeyN
+		return nil
+	})
+	return
+}
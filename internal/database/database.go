@@ -5,9 +5,13 @@ import (
 )
 
 type DataBase struct {
-	database  *bbolt.DB
-	RoomList  *RoomList
-	EventList *EventList
+	database    *bbolt.DB
+	RoomList    *RoomList
+	EventList   *EventList
+	PendingList *PendingList
+	AccessList  *AccessList
+	MessageList *MessageList
+	PollList    *PollList
 }
 
 func NewDataBase(path string) (db *DataBase, err error) {
@@ -30,6 +34,26 @@ func NewDataBase(path string) (db *DataBase, err error) {
 		return
 	}
 
+	db.PendingList, err = newPendingList(database)
+	if err != nil {
+		return
+	}
+
+	db.AccessList, err = newAccessList(database)
+	if err != nil {
+		return
+	}
+
+	db.MessageList, err = newMessageList(database)
+	if err != nil {
+		return
+	}
+
+	db.PollList, err = newPollList(database)
+	if err != nil {
+		return
+	}
+
 	return
 }
 
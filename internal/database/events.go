@@ -42,3 +42,7 @@ func (el *EventList) Set(id id.EventID) (err error) {
 	err = el.events.Put([]byte(id.String()), []byte{})
 	return
 }
+
+func (el *EventList) Count() (count int, err error) {
+	return el.events.Count()
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"bytes"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+	"maunium.net/go/mautrix/id"
+)
+
+// Telegram 消息和 Matrix 事件的对应关系，两个方向各有一个 bucket
+// telegram 的 key 是 ChatID + MessageID，matrix 的 key 是 EventID，value 都是 MessageInfo
+
+type MessageList struct {
+	telegram Bucket
+	matrix   Bucket
+}
+
+func newMessageList(db *bbolt.DB) (ml *MessageList, err error) {
+	ml = &MessageList{
+		telegram: Bucket{
+			database: db,
+			bucket:   []byte{bucketMessageListTelegram},
+			keyLen:   16,
+		},
+		matrix: Bucket{
+			database: db,
+			bucket:   []byte{bucketMessageListMatrix},
+			keyLen:   1,
+		},
+	}
+
+	for _, b := range []*Bucket{&ml.telegram, &ml.matrix} {
+		// 检查 bucket 是否存在
+		var exi bool
+		exi, err = b.Exists()
+		if err != nil {
+			return
+		}
+		// 如果不存在则创建
+		if !exi {
+			if err = b.Create(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func messageKey(chatID int64, messageID int) []byte {
+	return append(chatID2Bytes(chatID), chatID2Bytes(int64(messageID))...)
+}
+
+// 保存对应关系，两个方向在同一个事务里写入
+
+func (ml *MessageList) Put(info *types.MessageInfo) (err error) {
+	data, err := proto.Marshal(info)
+	if err != nil {
+		return
+	}
+	return ml.telegram.database.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(ml.matrix.bucket).Put([]byte(info.GetEventID()), data); err != nil {
+			return err
+		}
+		bucket := tx.Bucket(ml.telegram.bucket)
+		for _, messageID := range info.GetMessageIDs() {
+			if err := bucket.Put(messageKey(info.GetChatID(), int(messageID)), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func getMessageInfo(b *Bucket, key []byte) (info *types.MessageInfo, err error) {
+	data, err := b.Get(key)
+	if err != nil || data == nil {
+		return
+	}
+	info = &types.MessageInfo{}
+	err = proto.Unmarshal(data, info)
+	return
+}
+
+// 找不到的时候 info 为 nil
+
+func (ml *MessageList) GetByTelegram(chatID int64, messageID int) (info *types.MessageInfo, err error) {
+	return getMessageInfo(&ml.telegram, messageKey(chatID, messageID))
+}
+
+func (ml *MessageList) GetByEventID(eventID id.EventID) (info *types.MessageInfo, err error) {
+	return getMessageInfo(&ml.matrix, []byte(eventID))
+}
+
+func (ml *MessageList) Count() (count int, err error) {
+	return ml.matrix.Count()
+}
+
+// 删除 before（Unix 时间）之前保存的对应关系，两个方向在同一个事务里删除
+
+func (ml *MessageList) Prune(before int64) (removed int, err error) {
+	err = ml.telegram.database.Update(func(tx *bbolt.Tx) error {
+		matrix := tx.Bucket(ml.matrix.bucket)
+		telegram := tx.Bucket(ml.telegram.bucket)
+
+		// 遍历的时候不能删除，先把要删除的 key 记下来
+		var eventKeys, messageKeys [][]byte
+		err := matrix.ForEach(func(k, v []byte) error {
+			info := &types.MessageInfo{}
+			if err := proto.Unmarshal(v, info); err != nil {
+				return err
+			}
+			if info.GetCreatedAt() >= before {
+				return nil
+			}
+			eventKeys = append(eventKeys, append([]byte(nil), k...))
+			for _, messageID := range info.GetMessageIDs() {
+				key := messageKey(info.GetChatID(), int(messageID))
+				// 同一条 Telegram 消息可能已经对应到了新的事件
+				if existing := telegram.Get(key); existing != nil && !bytes.Equal(existing, v) {
+					continue
+				}
+				messageKeys = append(messageKeys, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range eventKeys {
+			if err := matrix.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, k := range messageKeys {
+			if err := telegram.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(eventKeys)
+		return nil
+	})
+	return
+}
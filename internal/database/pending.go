@@ -0,0 +1,90 @@
+package database
+
+import (
+	"sync"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+// 等待审批的联系人，key 是 ChatID
+
+type PendingList struct {
+	pending Bucket
+	// 检查审批和审批通过的时候按照 ChatID 加锁，保证缓存的消息转发完之前不会处理新的消息
+	ChatIDMutex sync.Map
+}
+
+func newPendingList(db *bbolt.DB) (pl *PendingList, err error) {
+	pl = &PendingList{
+		pending: Bucket{
+			database: db,
+			bucket:   []byte{bucketPendingListPending},
+			keyLen:   8,
+		},
+	}
+
+	// 检查 bucket 是否存在
+	exi, err := pl.pending.Exists()
+	if err != nil {
+		return
+	}
+	// 如果不存在则创建
+	if !exi {
+		err = pl.pending.Create()
+	}
+	return
+}
+
+func (pl *PendingList) Get(chatID int64) (info *types.PendingInfo, err error) {
+	data, err := pl.pending.Get(chatID2Bytes(chatID))
+	if err != nil || data == nil {
+		return
+	}
+	info = &types.PendingInfo{}
+	err = proto.Unmarshal(data, info)
+	return
+}
+
+// 修改等待审批的联系人，不存在的时候 update 会拿到一个空的 PendingInfo
+// 读取和写入在同一个事务里完成，update 返回 false 时不写入
+
+func (pl *PendingList) Update(chatID int64, update func(info *types.PendingInfo) bool) (info *types.PendingInfo, err error) {
+	err = pl.pending.database.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(pl.pending.bucket)
+		info = &types.PendingInfo{}
+		if data := bucket.Get(chatID2Bytes(chatID)); data != nil {
+			if err := proto.Unmarshal(data, info); err != nil {
+				return err
+			}
+		}
+		if !update(info) {
+			return nil
+		}
+		data, err := proto.Marshal(info)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(chatID2Bytes(chatID), data)
+	})
+	return
+}
+
+func (pl *PendingList) Delete(chatID int64) (err error) {
+	return pl.pending.Delete(chatID2Bytes(chatID))
+}
+
+// 遍历所有等待审批的联系人，fn 里不能修改数据库
+
+func (pl *PendingList) ForEach(fn func(info *types.PendingInfo) error) (err error) {
+	return pl.pending.database.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(pl.pending.bucket).ForEach(func(_, v []byte) error {
+			var info types.PendingInfo
+			if err := proto.Unmarshal(v, &info); err != nil {
+				return err
+			}
+			return fn(&info)
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"github.com/AsenHu/mewlink/internal/types"
+	"go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+	"maunium.net/go/mautrix/id"
+)
+
+// 投票的对应关系
+// polls 的 key 是 Telegram 的 PollID，value 是 PollInfo
+// events 的 key 是 Matrix 投票的 EventID，value 是 PollID
+
+type PollList struct {
+	polls  Bucket
+	events Bucket
+}
+
+func newPollList(db *bbolt.DB) (pl *PollList, err error) {
+	pl = &PollList{
+		polls: Bucket{
+			database: db,
+			bucket:   []byte{bucketPollListPolls},
+			keyLen:   1,
+		},
+		events: Bucket{
+			database: db,
+			bucket:   []byte{bucketPollListEvents},
+			keyLen:   1,
+		},
+	}
+
+	for _, b := range []*Bucket{&pl.polls, &pl.events} {
+		// 检查 bucket 是否存在
+		var exi bool
+		exi, err = b.Exists()
+		if err != nil {
+			return
+		}
+		// 如果不存在则创建
+		if !exi {
+			if err = b.Create(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// 保存投票，两个方向在同一个事务里写入
+
+func (pl *PollList) Put(info *types.PollInfo) (err error) {
+	data, err := proto.Marshal(info)
+	if err != nil {
+		return
+	}
+	return pl.polls.database.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(pl.polls.bucket).Put([]byte(info.GetPollID()), data); err != nil {
+			return err
+		}
+		return tx.Bucket(pl.events.bucket).Put([]byte(info.GetEventID()), []byte(info.GetPollID()))
+	})
+}
+
+// 找不到的时候 info 为 nil
+
+func (pl *PollList) GetByPollID(pollID string) (info *types.PollInfo, err error) {
+	data, err := pl.polls.Get([]byte(pollID))
+	if err != nil || data == nil {
+		return
+	}
+	info = &types.PollInfo{}
+	err = proto.Unmarshal(data, info)
+	return
+}
+
+func (pl *PollList) GetByEventID(eventID id.EventID) (info *types.PollInfo, err error) {
+	pollID, err := pl.events.Get([]byte(eventID))
+	if err != nil || pollID == nil {
+		return
+	}
+	return pl.GetByPollID(string(pollID))
+}
+
+// 标记投票已经结束，返回 false 代表之前已经结束了
+
+func (pl *PollList) Close(pollID string) (closed bool, err error) {
+	err = pl.polls.database.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(pl.polls.bucket)
+		data := bucket.Get([]byte(pollID))
+		if data == nil {
+			return nil
+		}
+		var info types.PollInfo
+		if err := proto.Unmarshal(data, &info); err != nil {
+			return err
+		}
+		if info.GetClosed() {
+			return nil
+		}
+		info.Closed = true
+		closed = true
+		data, err := proto.Marshal(&info)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(pollID), data)
+	})
+	return
+}
+
+// 删除 before（Unix 时间）之前保存的投票，两个方向在同一个事务里删除
+
+func (pl *PollList) Prune(before int64) (removed int, err error) {
+	err = pl.polls.database.Update(func(tx *bbolt.Tx) error {
+		polls := tx.Bucket(pl.polls.bucket)
+		events := tx.Bucket(pl.events.bucket)
+
+		// 遍历的时候不能删除，先把要删除的 key 记下来
+		var pollKeys, eventKeys [][]byte
+		err := polls.ForEach(func(k, v []byte) error {
+			info := &types.PollInfo{}
+			if err := proto.Unmarshal(v, info); err != nil {
+				return err
+			}
+			if info.GetCreatedAt() >= before {
+				return nil
+			}
+			pollKeys = append(pollKeys, append([]byte(nil), k...))
+			// 同一个事件可能已经对应到了新的投票
+			if pollID := events.Get([]byte(info.GetEventID())); pollID != nil && string(pollID) == info.GetPollID() {
+				eventKeys = append(eventKeys, []byte(info.GetEventID()))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range pollKeys {
+			if err := polls.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, k := range eventKeys {
+			if err := events.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(pollKeys)
+		return nil
+	})
+	return
+}
@@ -193,3 +193,162 @@ func (rl *RoomList) SetChatIDIndex(chatID int64, index []byte) (err error) {
 func (rl *RoomList) SetRoomIDIndex(roomID id.RoomID, index []byte) (err error) {
 	return rl.roomIDIndex.Put([]byte(roomID), index)
 }
+
+// 使用 ChatID 查询 RoomInfo
+// 会按照 ChatID -> index 的顺序上读锁，index 不存在时 roomInfo 为 nil
+
+func (rl *RoomList) GetRoomInfoByChatID(chatID int64) (index []byte, roomInfo *types.RoomInfo, err error) {
+	chatLock, _ := rl.ChatIDMutex.LoadOrStore(chatID, &sync.RWMutex{})
+	chatLock.(*sync.RWMutex).RLock()
+	index, err = rl.GetIndexByChatID(chatID)
+	chatLock.(*sync.RWMutex).RUnlock()
+	if err != nil || index == nil {
+		return
+	}
+
+	roomInfo, err = rl.getRoomInfoByIndexLocked(index)
+	return
+}
+
+// 使用 RoomID 查询 RoomInfo
+// 会按照 RoomID -> index 的顺序上读锁，index 不存在时 roomInfo 为 nil
+
+func (rl *RoomList) GetRoomInfoByRoomID(roomID id.RoomID) (index []byte, roomInfo *types.RoomInfo, err error) {
+	roomLock, _ := rl.RoomIDMutex.LoadOrStore(string(roomID), &sync.RWMutex{})
+	roomLock.(*sync.RWMutex).RLock()
+	index, err = rl.GetIndexByRoomID(roomID)
+	roomLock.(*sync.RWMutex).RUnlock()
+	if err != nil || index == nil {
+		return
+	}
+
+	roomInfo, err = rl.getRoomInfoByIndexLocked(index)
+	return
+}
+
+func (rl *RoomList) getRoomInfoByIndexLocked(index []byte) (roomInfo *types.RoomInfo, err error) {
+	indexLock, _ := rl.RoomInfoBucket.IndexMutex.LoadOrStore(string(index), &sync.RWMutex{})
+	indexLock.(*sync.RWMutex).RLock()
+	defer indexLock.(*sync.RWMutex).RUnlock()
+	return rl.GetRoomInfoByIndex(index)
+}
+
+// 修改 Index 对应的 RoomInfo
+// 读取和写入之间会一直持有 index 的写锁，update 返回 false 时不写入
+// 不能用来修改 ChatID 和 RoomID，因为这需要同时修改 index bucket
+
+func (rl *RoomList) UpdateRoomInfoByIndex(index []byte, update func(info *types.RoomInfo) bool) (roomInfo *types.RoomInfo, err error) {
+	indexLock, _ := rl.RoomInfoBucket.IndexMutex.LoadOrStore(string(index), &sync.RWMutex{})
+	indexLock.(*sync.RWMutex).Lock()
+	defer indexLock.(*sync.RWMutex).Unlock()
+
+	roomInfo, err = rl.GetRoomInfoByIndex(index)
+	if err != nil || roomInfo == nil {
+		return
+	}
+	if !update(roomInfo) {
+		return
+	}
+	err = rl.SetRoomInfoByIndex(index, roomInfo)
+	return
+}
+
+// 把 Index 对应的 RoomInfo 迁移到新的 RoomID
+// newRoom 会在持有 index 写锁的时候调用，可以顺便修改 RoomInfo 的其他字段，返回空的 RoomID 代表不需要迁移
+// RoomInfo 和 RoomID index 的修改在同一个事务里完成
+
+func (rl *RoomList) MoveRoomByIndex(index []byte, newRoom func(info *types.RoomInfo) (id.RoomID, error)) (roomInfo *types.RoomInfo, err error) {
+	indexLock, _ := rl.RoomInfoBucket.IndexMutex.LoadOrStore(string(index), &sync.RWMutex{})
+	indexLock.(*sync.RWMutex).Lock()
+	defer indexLock.(*sync.RWMutex).Unlock()
+
+	roomInfo, err = rl.GetRoomInfoByIndex(index)
+	if err != nil || roomInfo == nil {
+		return
+	}
+	oldRoomID := id.RoomID(roomInfo.GetRoomID())
+	roomID, err := newRoom(roomInfo)
+	if err != nil || roomID == "" || roomID == oldRoomID {
+		return
+	}
+	roomInfo.RoomID = string(roomID)
+
+	// 按照固定的顺序锁定两个 RoomID，避免死锁
+	first, second := string(oldRoomID), string(roomID)
+	if first > second {
+		first, second = second, first
+	}
+	firstLock, _ := rl.RoomIDMutex.LoadOrStore(first, &sync.RWMutex{})
+	firstLock.(*sync.RWMutex).Lock()
+	defer firstLock.(*sync.RWMutex).Unlock()
+	secondLock, _ := rl.RoomIDMutex.LoadOrStore(second, &sync.RWMutex{})
+	secondLock.(*sync.RWMutex).Lock()
+	defer secondLock.(*sync.RWMutex).Unlock()
+
+	data, err := proto.Marshal(roomInfo)
+	if err != nil {
+		return
+	}
+	err = rl.RoomInfoBucket.database.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(rl.RoomInfoBucket.bucket).Put(index, data); err != nil {
+			return err
+		}
+		if err := tx.Bucket(rl.roomIDIndex.bucket).Delete([]byte(oldRoomID)); err != nil {
+			return err
+		}
+		return tx.Bucket(rl.roomIDIndex.bucket).Put([]byte(roomID), index)
+	})
+	return
+}
+
+// 删除 Index 对应的 RoomInfo 以及两个 index
+// 锁的顺序和创建房间的时候一样：ChatID -> index -> RoomID
+
+func (rl *RoomList) DeleteRoomInfoByIndex(index []byte) (err error) {
+	roomInfo, err := rl.getRoomInfoByIndexLocked(index)
+	if err != nil || roomInfo == nil {
+		return
+	}
+
+	chatLock, _ := rl.ChatIDMutex.LoadOrStore(roomInfo.GetChatID(), &sync.RWMutex{})
+	chatLock.(*sync.RWMutex).Lock()
+	defer chatLock.(*sync.RWMutex).Unlock()
+	indexLock, _ := rl.RoomInfoBucket.IndexMutex.LoadOrStore(string(index), &sync.RWMutex{})
+	indexLock.(*sync.RWMutex).Lock()
+	defer indexLock.(*sync.RWMutex).Unlock()
+
+	// 拿到 index 锁之前 RoomID 可能被修改过，需要重新读取
+	roomInfo, err = rl.GetRoomInfoByIndex(index)
+	if err != nil || roomInfo == nil {
+		return
+	}
+	roomLock, _ := rl.RoomIDMutex.LoadOrStore(roomInfo.GetRoomID(), &sync.RWMutex{})
+	roomLock.(*sync.RWMutex).Lock()
+	defer roomLock.(*sync.RWMutex).Unlock()
+
+	err = rl.RoomInfoBucket.database.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(rl.RoomInfoBucket.bucket).Delete(index); err != nil {
+			return err
+		}
+		if err := tx.Bucket(rl.chatIDIndex.bucket).Delete(chatID2Bytes(roomInfo.GetChatID())); err != nil {
+			return err
+		}
+		return tx.Bucket(rl.roomIDIndex.bucket).Delete([]byte(roomInfo.GetRoomID()))
+	})
+	return
+}
+
+// 遍历所有的 RoomInfo
+// 在只读事务里完成，fn 里不能修改数据库，index 也只在 fn 里有效
+
+func (rl *RoomList) ForEachRoomInfo(fn func(index []byte, roomInfo *types.RoomInfo) error) (err error) {
+	return rl.RoomInfoBucket.database.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(rl.RoomInfoBucket.bucket).ForEach(func(k, v []byte) error {
+			var roomInfo types.RoomInfo
+			if err := proto.Unmarshal(v, &roomInfo); err != nil {
+				return err
+			}
+			return fn(k, &roomInfo)
+		})
+	})
+}
@@ -0,0 +1,231 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+/*
+发送到 Telegram 的消息的调度器
+
+Telegram 限制 Bot 每秒最多发送 30 条消息，每个聊天每秒最多 1 条
+所有写入 Telegram 的请求都要经过这里排队，超过限制的请求会等待，而不是直接失败
+收到 429 的时候按照 retry_after 暂停这个聊天，然后重试
+*/
+
+const (
+	globalInterval = time.Second / 30
+	chatInterval   = time.Second
+	// 收到 429 之后最多重试的次数
+	maxRetries = 5
+	// 聊天数量超过这个值的时候，清理掉已经可以发送的聊天
+	maxChats = 1024
+)
+
+type Outbox struct {
+	telegram *bot.Bot
+	mutex    sync.Mutex
+	// 下一个可以发送的时间
+	next  time.Time
+	chats map[any]time.Time
+}
+
+func New(telegram *bot.Bot) *Outbox {
+	return &Outbox{
+		telegram: telegram,
+		chats:    map[any]time.Time{},
+	}
+}
+
+// 预约一个发送时间，并更新下一个可以发送的时间
+
+func reserve(next *time.Time, now time.Time, interval time.Duration) (slot time.Time) {
+	slot = *next
+	if slot.Before(now) {
+		slot = now
+	}
+	*next = slot.Add(interval)
+	return
+}
+
+func (o *Outbox) reserveChat(chatID any) time.Time {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	now := time.Now()
+	if len(o.chats) >= maxChats {
+		for chat, next := range o.chats {
+			if next.Before(now) {
+				delete(o.chats, chat)
+			}
+		}
+	}
+	next := o.chats[chatID]
+	slot := reserve(&next, now, chatInterval)
+	o.chats[chatID] = next
+	return slot
+}
+
+func (o *Outbox) reserveGlobal() time.Time {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return reserve(&o.next, time.Now(), globalInterval)
+}
+
+// 暂停一个聊天，收到 429 的时候使用
+
+func (o *Outbox) pause(chatID any, d time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if until := time.Now().Add(d); o.chats[chatID].Before(until) {
+		o.chats[chatID] = until
+	}
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// 先等待聊天的限制，再等待全局的限制
+
+func (o *Outbox) wait(ctx context.Context, chatID any) (err error) {
+	if err = sleepUntil(ctx, o.reserveChat(chatID)); err != nil {
+		return
+	}
+	return sleepUntil(ctx, o.reserveGlobal())
+}
+
+// 排队执行一个写入 Telegram 的请求，收到 429 的时候自动重试
+
+func Do[T any](ctx context.Context, o *Outbox, chatID any, call func(ctx context.Context) (T, error)) (result T, err error) {
+	for attempt := 0; ; attempt++ {
+		if err = o.wait(ctx, chatID); err != nil {
+			return
+		}
+		result, err = call(ctx)
+
+		var tooMany *bot.TooManyRequestsError
+		if !errors.As(err, &tooMany) || attempt >= maxRetries {
+			return
+		}
+		retryAfter := time.Duration(max(tooMany.RetryAfter, 1)) * time.Second
+		log.Warn().
+			Any("ChatID", chatID).
+			Dur("RetryAfter", retryAfter).
+			Int("Attempt", attempt+1).
+			Msg("Telegram flood control, retrying")
+		o.pause(chatID, retryAfter)
+	}
+}
+
+func (o *Outbox) SendMessage(ctx context.Context, params *bot.SendMessageParams) (*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Message, error) {
+		return o.telegram.SendMessage(ctx, params)
+	})
+}
+
+// 上传的文件在重试的时候会重新读取，如果 Data 实现了 io.Seeker，每次发送之前会回到开头
+
+func rewind(file models.InputFile) (err error) {
+	upload, ok := file.(*models.InputFileUpload)
+	if !ok {
+		return
+	}
+	if seeker, ok := upload.Data.(io.Seeker); ok {
+		_, err = seeker.Seek(0, io.SeekStart)
+	}
+	return
+}
+
+func (o *Outbox) SendDocument(ctx context.Context, params *bot.SendDocumentParams) (*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Message, error) {
+		if err := rewind(params.Document); err != nil {
+			return nil, err
+		}
+		return o.telegram.SendDocument(ctx, params)
+	})
+}
+
+func (o *Outbox) SendLocation(ctx context.Context, params *bot.SendLocationParams) (*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Message, error) {
+		return o.telegram.SendLocation(ctx, params)
+	})
+}
+
+func (o *Outbox) SendVenue(ctx context.Context, params *bot.SendVenueParams) (*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Message, error) {
+		return o.telegram.SendVenue(ctx, params)
+	})
+}
+
+func (o *Outbox) SendContact(ctx context.Context, params *bot.SendContactParams) (*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Message, error) {
+		return o.telegram.SendContact(ctx, params)
+	})
+}
+
+func (o *Outbox) SendPoll(ctx context.Context, params *bot.SendPollParams) (*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Message, error) {
+		return o.telegram.SendPoll(ctx, params)
+	})
+}
+
+func (o *Outbox) StopPoll(ctx context.Context, params *bot.StopPollParams) (*models.Poll, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Poll, error) {
+		return o.telegram.StopPoll(ctx, params)
+	})
+}
+
+func (o *Outbox) SendPhoto(ctx context.Context, params *bot.SendPhotoParams) (*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (*models.Message, error) {
+		if err := rewind(params.Photo); err != nil {
+			return nil, err
+		}
+		return o.telegram.SendPhoto(ctx, params)
+	})
+}
+
+func (o *Outbox) SendMediaGroup(ctx context.Context, params *bot.SendMediaGroupParams) ([]*models.Message, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) ([]*models.Message, error) {
+		for _, media := range params.Media {
+			if seeker, ok := media.Attachment().(io.Seeker); ok {
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return o.telegram.SendMediaGroup(ctx, params)
+	})
+}
+
+// 聊天动作不是消息，不占用聊天的发送间隔，过期很快，失败了也不重试
+
+func (o *Outbox) SendChatAction(ctx context.Context, params *bot.SendChatActionParams) (bool, error) {
+	if err := sleepUntil(ctx, o.reserveGlobal()); err != nil {
+		return false, err
+	}
+	return o.telegram.SendChatAction(ctx, params)
+}
+
+func (o *Outbox) SetMessageReaction(ctx context.Context, params *bot.SetMessageReactionParams) (bool, error) {
+	return Do(ctx, o, params.ChatID, func(ctx context.Context) (bool, error) {
+		return o.telegram.SetMessageReaction(ctx, params)
+	})
+}
@@ -0,0 +1,179 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.5
+// 	protoc        v6.30.0--rc1
+// source: protos/messageinfo.proto
+
+package types
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Telegram 消息和 Matrix 事件的对应关系
+// 一个 Matrix 事件可能会被拆分成多条 Telegram 消息
+type MessageInfo struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ChatID     int64                  `protobuf:"varint,1,opt,name=ChatID,proto3" json:"ChatID,omitempty"`
+	MessageIDs []int64                `protobuf:"varint,2,rep,packed,name=MessageIDs,proto3" json:"MessageIDs,omitempty"`
+	RoomID     string                 `protobuf:"bytes,3,opt,name=RoomID,proto3" json:"RoomID,omitempty"`
+	EventID    string                 `protobuf:"bytes,4,opt,name=EventID,proto3" json:"EventID,omitempty"`
+	CreatedAt  int64                  `protobuf:"varint,5,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	// 事件所在的讨论串的根事件，不在讨论串里的时候为空
+	ThreadID      string `protobuf:"bytes,6,opt,name=ThreadID,proto3" json:"ThreadID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageInfo) Reset() {
+	*x = MessageInfo{}
+	mi := &file_protos_messageinfo_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageInfo) ProtoMessage() {}
+
+func (x *MessageInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messageinfo_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageInfo.ProtoReflect.Descriptor instead.
+func (*MessageInfo) Descriptor() ([]byte, []int) {
+	return file_protos_messageinfo_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MessageInfo) GetChatID() int64 {
+	if x != nil {
+		return x.ChatID
+	}
+	return 0
+}
+
+func (x *MessageInfo) GetMessageIDs() []int64 {
+	if x != nil {
+		return x.MessageIDs
+	}
+	return nil
+}
+
+func (x *MessageInfo) GetRoomID() string {
+	if x != nil {
+		return x.RoomID
+	}
+	return ""
+}
+
+func (x *MessageInfo) GetEventID() string {
+	if x != nil {
+		return x.EventID
+	}
+	return ""
+}
+
+func (x *MessageInfo) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *MessageInfo) GetThreadID() string {
+	if x != nil {
+		return x.ThreadID
+	}
+	return ""
+}
+
+var File_protos_messageinfo_proto protoreflect.FileDescriptor
+
+var file_protos_messageinfo_proto_rawDesc = string([]byte{
+	0x0a, 0x18, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x69, 0x6e, 0x66, 0x6f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xb1, 0x01, 0x0a, 0x0b, 0x4d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x43, 0x68,
+	0x61, 0x74, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x43, 0x68, 0x61, 0x74,
+	0x49, 0x44, 0x12, 0x1e, 0x0a, 0x0a, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x03, 0x52, 0x0a, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49,
+	0x44, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x52, 0x6f, 0x6f, 0x6d, 0x49, 0x44, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x52, 0x6f, 0x6f, 0x6d, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x49, 0x44, 0x12, 0x1c, 0x0a, 0x09, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41,
+	0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64,
+	0x41, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x54, 0x68, 0x72, 0x65, 0x61, 0x64, 0x49, 0x44, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x54, 0x68, 0x72, 0x65, 0x61, 0x64, 0x49, 0x44, 0x42, 0x2a,
+	0x5a, 0x28, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x73, 0x65,
+	0x6e, 0x48, 0x75, 0x2f, 0x6d, 0x65, 0x77, 0x6c, 0x69, 0x6e, 0x6b, 0x2f, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+})
+
+var (
+	file_protos_messageinfo_proto_rawDescOnce sync.Once
+	file_protos_messageinfo_proto_rawDescData []byte
+)
+
+func file_protos_messageinfo_proto_rawDescGZIP() []byte {
+	file_protos_messageinfo_proto_rawDescOnce.Do(func() {
+		file_protos_messageinfo_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protos_messageinfo_proto_rawDesc), len(file_protos_messageinfo_proto_rawDesc)))
+	})
+	return file_protos_messageinfo_proto_rawDescData
+}
+
+var file_protos_messageinfo_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_protos_messageinfo_proto_goTypes = []any{
+	(*MessageInfo)(nil), // 0: MessageInfo
+}
+var file_protos_messageinfo_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_protos_messageinfo_proto_init() }
+func file_protos_messageinfo_proto_init() {
+	if File_protos_messageinfo_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_messageinfo_proto_rawDesc), len(file_protos_messageinfo_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_protos_messageinfo_proto_goTypes,
+		DependencyIndexes: file_protos_messageinfo_proto_depIdxs,
+		MessageInfos:      file_protos_messageinfo_proto_msgTypes,
+	}.Build()
+	File_protos_messageinfo_proto = out.File
+	file_protos_messageinfo_proto_goTypes = nil
+	file_protos_messageinfo_proto_depIdxs = nil
+}
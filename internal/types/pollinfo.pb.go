@@ -0,0 +1,207 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.5
+// 	protoc        v6.30.0--rc1
+// source: protos/pollinfo.proto
+
+package types
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Telegram 投票和 Matrix 投票（MSC3381）的对应关系
+type PollInfo struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	PollID    string                 `protobuf:"bytes,1,opt,name=PollID,proto3" json:"PollID,omitempty"`
+	ChatID    int64                  `protobuf:"varint,2,opt,name=ChatID,proto3" json:"ChatID,omitempty"`
+	MessageID int64                  `protobuf:"varint,3,opt,name=MessageID,proto3" json:"MessageID,omitempty"`
+	RoomID    string                 `protobuf:"bytes,4,opt,name=RoomID,proto3" json:"RoomID,omitempty"`
+	EventID   string                 `protobuf:"bytes,5,opt,name=EventID,proto3" json:"EventID,omitempty"`
+	// Matrix 上的选项 ID，下标是 Telegram 上的选项序号
+	AnswerIDs []string `protobuf:"bytes,6,rep,name=AnswerIDs,proto3" json:"AnswerIDs,omitempty"`
+	Closed    bool     `protobuf:"varint,7,opt,name=Closed,proto3" json:"Closed,omitempty"`
+	CreatedAt int64    `protobuf:"varint,8,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	// 投票是被服务的用户在 Matrix 上发起的，Bot 只能结束自己发出的投票
+	FromMatrix    bool `protobuf:"varint,9,opt,name=FromMatrix,proto3" json:"FromMatrix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PollInfo) Reset() {
+	*x = PollInfo{}
+	mi := &file_protos_pollinfo_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollInfo) ProtoMessage() {}
+
+func (x *PollInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_pollinfo_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollInfo.ProtoReflect.Descriptor instead.
+func (*PollInfo) Descriptor() ([]byte, []int) {
+	return file_protos_pollinfo_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PollInfo) GetPollID() string {
+	if x != nil {
+		return x.PollID
+	}
+	return ""
+}
+
+func (x *PollInfo) GetChatID() int64 {
+	if x != nil {
+		return x.ChatID
+	}
+	return 0
+}
+
+func (x *PollInfo) GetMessageID() int64 {
+	if x != nil {
+		return x.MessageID
+	}
+	return 0
+}
+
+func (x *PollInfo) GetRoomID() string {
+	if x != nil {
+		return x.RoomID
+	}
+	return ""
+}
+
+func (x *PollInfo) GetEventID() string {
+	if x != nil {
+		return x.EventID
+	}
+	return ""
+}
+
+func (x *PollInfo) GetAnswerIDs() []string {
+	if x != nil {
+		return x.AnswerIDs
+	}
+	return nil
+}
+
+func (x *PollInfo) GetClosed() bool {
+	if x != nil {
+		return x.Closed
+	}
+	return false
+}
+
+func (x *PollInfo) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *PollInfo) GetFromMatrix() bool {
+	if x != nil {
+		return x.FromMatrix
+	}
+	return false
+}
+
+var File_protos_pollinfo_proto protoreflect.FileDescriptor
+
+var file_protos_pollinfo_proto_rawDesc = string([]byte{
+	0x0a, 0x15, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x70, 0x6f, 0x6c, 0x6c, 0x69, 0x6e, 0x66,
+	0x6f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xfe, 0x01, 0x0a, 0x08, 0x50, 0x6f, 0x6c, 0x6c,
+	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x50, 0x6f, 0x6c, 0x6c, 0x49, 0x44, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x50, 0x6f, 0x6c, 0x6c, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06,
+	0x43, 0x68, 0x61, 0x74, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x43, 0x68,
+	0x61, 0x74, 0x49, 0x44, 0x12, 0x1c, 0x0a, 0x09, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49,
+	0x44, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x52, 0x6f, 0x6f, 0x6d, 0x49, 0x44, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x52, 0x6f, 0x6f, 0x6d, 0x49, 0x44, 0x12, 0x18, 0x0a, 0x07, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x49, 0x44, 0x12, 0x1c, 0x0a, 0x09, 0x41, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x49, 0x44,
+	0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x41, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x49,
+	0x44, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x06, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x43, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x43,
+	0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x46, 0x72, 0x6f, 0x6d,
+	0x4d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x46, 0x72,
+	0x6f, 0x6d, 0x4d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x73, 0x65, 0x6e, 0x48, 0x75, 0x2f, 0x6d, 0x65,
+	0x77, 0x6c, 0x69, 0x6e, 0x6b, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x74,
+	0x79, 0x70, 0x65, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+})
+
+var (
+	file_protos_pollinfo_proto_rawDescOnce sync.Once
+	file_protos_pollinfo_proto_rawDescData []byte
+)
+
+func file_protos_pollinfo_proto_rawDescGZIP() []byte {
+	file_protos_pollinfo_proto_rawDescOnce.Do(func() {
+		file_protos_pollinfo_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protos_pollinfo_proto_rawDesc), len(file_protos_pollinfo_proto_rawDesc)))
+	})
+	return file_protos_pollinfo_proto_rawDescData
+}
+
+var file_protos_pollinfo_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_protos_pollinfo_proto_goTypes = []any{
+	(*PollInfo)(nil), // 0: PollInfo
+}
+var file_protos_pollinfo_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_protos_pollinfo_proto_init() }
+func file_protos_pollinfo_proto_init() {
+	if File_protos_pollinfo_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_pollinfo_proto_rawDesc), len(file_protos_pollinfo_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_protos_pollinfo_proto_goTypes,
+		DependencyIndexes: file_protos_pollinfo_proto_depIdxs,
+		MessageInfos:      file_protos_pollinfo_proto_msgTypes,
+	}.Build()
+	File_protos_pollinfo_proto = out.File
+	file_protos_pollinfo_proto_goTypes = nil
+	file_protos_pollinfo_proto_depIdxs = nil
+}
@@ -21,14 +21,177 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Telegram 联系人的状态
+type RoomInfo_ContactStatus int32
+
+const (
+	RoomInfo_Active      RoomInfo_ContactStatus = 0
+	RoomInfo_Blocked     RoomInfo_ContactStatus = 1
+	RoomInfo_Deactivated RoomInfo_ContactStatus = 2
+)
+
+// Enum value maps for RoomInfo_ContactStatus.
+var (
+	RoomInfo_ContactStatus_name = map[int32]string{
+		0: "Active",
+		1: "Blocked",
+		2: "Deactivated",
+	}
+	RoomInfo_ContactStatus_value = map[string]int32{
+		"Active":      0,
+		"Blocked":     1,
+		"Deactivated": 2,
+	}
+)
+
+func (x RoomInfo_ContactStatus) Enum() *RoomInfo_ContactStatus {
+	p := new(RoomInfo_ContactStatus)
+	*p = x
+	return p
+}
+
+func (x RoomInfo_ContactStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RoomInfo_ContactStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_roominfo_proto_enumTypes[0].Descriptor()
+}
+
+func (RoomInfo_ContactStatus) Type() protoreflect.EnumType {
+	return &file_protos_roominfo_proto_enumTypes[0]
+}
+
+func (x RoomInfo_ContactStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RoomInfo_ContactStatus.Descriptor instead.
+func (RoomInfo_ContactStatus) EnumDescriptor() ([]byte, []int) {
+	return file_protos_roominfo_proto_rawDescGZIP(), []int{0, 0}
+}
+
+// 被服务的用户离开房间之后，房间和联系人之间的关系
+type RoomInfo_LinkStatus int32
+
+const (
+	RoomInfo_Linked   RoomInfo_LinkStatus = 0
+	RoomInfo_Dormant  RoomInfo_LinkStatus = 1
+	RoomInfo_Archived RoomInfo_LinkStatus = 2
+)
+
+// Enum value maps for RoomInfo_LinkStatus.
+var (
+	RoomInfo_LinkStatus_name = map[int32]string{
+		0: "Linked",
+		1: "Dormant",
+		2: "Archived",
+	}
+	RoomInfo_LinkStatus_value = map[string]int32{
+		"Linked":   0,
+		"Dormant":  1,
+		"Archived": 2,
+	}
+)
+
+func (x RoomInfo_LinkStatus) Enum() *RoomInfo_LinkStatus {
+	p := new(RoomInfo_LinkStatus)
+	*p = x
+	return p
+}
+
+func (x RoomInfo_LinkStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RoomInfo_LinkStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_roominfo_proto_enumTypes[1].Descriptor()
+}
+
+func (RoomInfo_LinkStatus) Type() protoreflect.EnumType {
+	return &file_protos_roominfo_proto_enumTypes[1]
+}
+
+func (x RoomInfo_LinkStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RoomInfo_LinkStatus.Descriptor instead.
+func (RoomInfo_LinkStatus) EnumDescriptor() ([]byte, []int) {
+	return file_protos_roominfo_proto_rawDescGZIP(), []int{0, 1}
+}
+
+// 是否把被服务的用户的已读回执转发给联系人，ReceiptDefault 的时候使用全局设置
+type RoomInfo_ReceiptMode int32
+
+const (
+	RoomInfo_ReceiptDefault RoomInfo_ReceiptMode = 0
+	RoomInfo_ReceiptOn      RoomInfo_ReceiptMode = 1
+	RoomInfo_ReceiptOff     RoomInfo_ReceiptMode = 2
+)
+
+// Enum value maps for RoomInfo_ReceiptMode.
+var (
+	RoomInfo_ReceiptMode_name = map[int32]string{
+		0: "ReceiptDefault",
+		1: "ReceiptOn",
+		2: "ReceiptOff",
+	}
+	RoomInfo_ReceiptMode_value = map[string]int32{
+		"ReceiptDefault": 0,
+		"ReceiptOn":      1,
+		"ReceiptOff":     2,
+	}
+)
+
+func (x RoomInfo_ReceiptMode) Enum() *RoomInfo_ReceiptMode {
+	p := new(RoomInfo_ReceiptMode)
+	*p = x
+	return p
+}
+
+func (x RoomInfo_ReceiptMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RoomInfo_ReceiptMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_roominfo_proto_enumTypes[2].Descriptor()
+}
+
+func (RoomInfo_ReceiptMode) Type() protoreflect.EnumType {
+	return &file_protos_roominfo_proto_enumTypes[2]
+}
+
+func (x RoomInfo_ReceiptMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RoomInfo_ReceiptMode.Descriptor instead.
+func (RoomInfo_ReceiptMode) EnumDescriptor() ([]byte, []int) {
+	return file_protos_roominfo_proto_rawDescGZIP(), []int{0, 2}
+}
+
 type RoomInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ChatID        int64                  `protobuf:"varint,1,opt,name=ChatID,proto3" json:"ChatID,omitempty"`
-	RoomID        string                 `protobuf:"bytes,2,opt,name=RoomID,proto3" json:"RoomID,omitempty"`
-	RoomName      string                 `protobuf:"bytes,3,opt,name=RoomName,proto3" json:"RoomName,omitempty"`
-	Avatar        string                 `protobuf:"bytes,4,opt,name=Avatar,proto3" json:"Avatar,omitempty"`
-	PinRoomName   bool                   `protobuf:"varint,5,opt,name=PinRoomName,proto3" json:"PinRoomName,omitempty"`
-	PinAvatar     bool                   `protobuf:"varint,6,opt,name=PinAvatar,proto3" json:"PinAvatar,omitempty"`
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ChatID           int64                  `protobuf:"varint,1,opt,name=ChatID,proto3" json:"ChatID,omitempty"`
+	RoomID           string                 `protobuf:"bytes,2,opt,name=RoomID,proto3" json:"RoomID,omitempty"`
+	RoomName         string                 `protobuf:"bytes,3,opt,name=RoomName,proto3" json:"RoomName,omitempty"`
+	Avatar           string                 `protobuf:"bytes,4,opt,name=Avatar,proto3" json:"Avatar,omitempty"`
+	PinRoomName      bool                   `protobuf:"varint,5,opt,name=PinRoomName,proto3" json:"PinRoomName,omitempty"`
+	PinAvatar        bool                   `protobuf:"varint,6,opt,name=PinAvatar,proto3" json:"PinAvatar,omitempty"`
+	Status           RoomInfo_ContactStatus `protobuf:"varint,7,opt,name=Status,proto3,enum=RoomInfo_ContactStatus" json:"Status,omitempty"`
+	Link             RoomInfo_LinkStatus    `protobuf:"varint,8,opt,name=Link,proto3,enum=RoomInfo_LinkStatus" json:"Link,omitempty"`
+	AvatarUniqueID   string                 `protobuf:"bytes,9,opt,name=AvatarUniqueID,proto3" json:"AvatarUniqueID,omitempty"`
+	ProfileUpdatedAt int64                  `protobuf:"varint,10,opt,name=ProfileUpdatedAt,proto3" json:"ProfileUpdatedAt,omitempty"`
+	Ignored          bool                   `protobuf:"varint,11,opt,name=Ignored,proto3" json:"Ignored,omitempty"`
+	Username         string                 `protobuf:"bytes,12,opt,name=Username,proto3" json:"Username,omitempty"`
+	CreatedAt        int64                  `protobuf:"varint,13,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	// 因为刷屏被禁言到这个时间，Unix 时间戳
+	MutedUntil int64                `protobuf:"varint,14,opt,name=MutedUntil,proto3" json:"MutedUntil,omitempty"`
+	Receipts   RoomInfo_ReceiptMode `protobuf:"varint,15,opt,name=Receipts,proto3,enum=RoomInfo_ReceiptMode" json:"Receipts,omitempty"`
+	// 联系人最后一条转发到 Matrix 的消息，和已经标记为已读的消息
+	LastMessageID int64 `protobuf:"varint,16,opt,name=LastMessageID,proto3" json:"LastMessageID,omitempty"`
+	SeenMessageID int64 `protobuf:"varint,17,opt,name=SeenMessageID,proto3" json:"SeenMessageID,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -105,11 +268,88 @@ func (x *RoomInfo) GetPinAvatar() bool {
 	return false
 }
 
+func (x *RoomInfo) GetStatus() RoomInfo_ContactStatus {
+	if x != nil {
+		return x.Status
+	}
+	return RoomInfo_Active
+}
+
+func (x *RoomInfo) GetLink() RoomInfo_LinkStatus {
+	if x != nil {
+		return x.Link
+	}
+	return RoomInfo_Linked
+}
+
+func (x *RoomInfo) GetAvatarUniqueID() string {
+	if x != nil {
+		return x.AvatarUniqueID
+	}
+	return ""
+}
+
+func (x *RoomInfo) GetProfileUpdatedAt() int64 {
+	if x != nil {
+		return x.ProfileUpdatedAt
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetIgnored() bool {
+	if x != nil {
+		return x.Ignored
+	}
+	return false
+}
+
+func (x *RoomInfo) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *RoomInfo) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetMutedUntil() int64 {
+	if x != nil {
+		return x.MutedUntil
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetReceipts() RoomInfo_ReceiptMode {
+	if x != nil {
+		return x.Receipts
+	}
+	return RoomInfo_ReceiptDefault
+}
+
+func (x *RoomInfo) GetLastMessageID() int64 {
+	if x != nil {
+		return x.LastMessageID
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetSeenMessageID() int64 {
+	if x != nil {
+		return x.SeenMessageID
+	}
+	return 0
+}
+
 var File_protos_roominfo_proto protoreflect.FileDescriptor
 
 var file_protos_roominfo_proto_rawDesc = string([]byte{
 	0x0a, 0x15, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x72, 0x6f, 0x6f, 0x6d, 0x69, 0x6e, 0x66,
-	0x6f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xae, 0x01, 0x0a, 0x08, 0x52, 0x6f, 0x6f, 0x6d,
+	0x6f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x82, 0x06, 0x0a, 0x08, 0x52, 0x6f, 0x6f, 0x6d,
 	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x43, 0x68, 0x61, 0x74, 0x49, 0x44, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x43, 0x68, 0x61, 0x74, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06,
 	0x52, 0x6f, 0x6f, 0x6d, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x52, 0x6f,
@@ -120,10 +360,47 @@ var file_protos_roominfo_proto_rawDesc = string([]byte{
 	0x6f, 0x6f, 0x6d, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x50,
 	0x69, 0x6e, 0x52, 0x6f, 0x6f, 0x6d, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x50, 0x69,
 	0x6e, 0x41, 0x76, 0x61, 0x74, 0x61, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x50,
-	0x69, 0x6e, 0x41, 0x76, 0x61, 0x74, 0x61, 0x72, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68,
-	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x73, 0x65, 0x6e, 0x48, 0x75, 0x2f, 0x6d, 0x65,
-	0x77, 0x6c, 0x69, 0x6e, 0x6b, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x74,
-	0x79, 0x70, 0x65, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x69, 0x6e, 0x41, 0x76, 0x61, 0x74, 0x61, 0x72, 0x12, 0x2f, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x52, 0x6f, 0x6f, 0x6d, 0x49,
+	0x6e, 0x66, 0x6f, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x28, 0x0a, 0x04, 0x4c, 0x69, 0x6e,
+	0x6b, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x52, 0x6f, 0x6f, 0x6d, 0x49, 0x6e,
+	0x66, 0x6f, 0x2e, 0x4c, 0x69, 0x6e, 0x6b, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x04, 0x4c,
+	0x69, 0x6e, 0x6b, 0x12, 0x26, 0x0a, 0x0e, 0x41, 0x76, 0x61, 0x74, 0x61, 0x72, 0x55, 0x6e, 0x69,
+	0x71, 0x75, 0x65, 0x49, 0x44, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x41, 0x76, 0x61,
+	0x74, 0x61, 0x72, 0x55, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x49, 0x44, 0x12, 0x2a, 0x0a, 0x10, 0x50,
+	0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x49, 0x67, 0x6e, 0x6f, 0x72,
+	0x65, 0x64, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x49, 0x67, 0x6e, 0x6f, 0x72, 0x65,
+	0x64, 0x12, 0x1a, 0x0a, 0x08, 0x55, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x55, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a,
+	0x09, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x4d,
+	0x75, 0x74, 0x65, 0x64, 0x55, 0x6e, 0x74, 0x69, 0x6c, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0a, 0x4d, 0x75, 0x74, 0x65, 0x64, 0x55, 0x6e, 0x74, 0x69, 0x6c, 0x12, 0x31, 0x0a, 0x08, 0x52,
+	0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x73, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e,
+	0x52, 0x6f, 0x6f, 0x6d, 0x49, 0x6e, 0x66, 0x6f, 0x2e, 0x52, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74,
+	0x4d, 0x6f, 0x64, 0x65, 0x52, 0x08, 0x52, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x73, 0x12, 0x24,
+	0x0a, 0x0d, 0x4c, 0x61, 0x73, 0x74, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44, 0x18,
+	0x10, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x4c, 0x61, 0x73, 0x74, 0x4d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x49, 0x44, 0x12, 0x24, 0x0a, 0x0d, 0x53, 0x65, 0x65, 0x6e, 0x4d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x49, 0x44, 0x18, 0x11, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x53, 0x65, 0x65,
+	0x6e, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x44, 0x22, 0x39, 0x0a, 0x0d, 0x43, 0x6f,
+	0x6e, 0x74, 0x61, 0x63, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0a, 0x0a, 0x06, 0x41,
+	0x63, 0x74, 0x69, 0x76, 0x65, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x65, 0x64, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x44, 0x65, 0x61, 0x63, 0x74, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x64, 0x10, 0x02, 0x22, 0x33, 0x0a, 0x0a, 0x4c, 0x69, 0x6e, 0x6b, 0x53, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x12, 0x0a, 0x0a, 0x06, 0x4c, 0x69, 0x6e, 0x6b, 0x65, 0x64, 0x10, 0x00, 0x12,
+	0x0b, 0x0a, 0x07, 0x44, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x74, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08,
+	0x41, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65, 0x64, 0x10, 0x02, 0x22, 0x40, 0x0a, 0x0b, 0x52, 0x65,
+	0x63, 0x65, 0x69, 0x70, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x52, 0x65, 0x63,
+	0x65, 0x69, 0x70, 0x74, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x10, 0x00, 0x12, 0x0d, 0x0a,
+	0x09, 0x52, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x4f, 0x6e, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a,
+	0x52, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x4f, 0x66, 0x66, 0x10, 0x02, 0x42, 0x2a, 0x5a, 0x28,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x73, 0x65, 0x6e, 0x48,
+	0x75, 0x2f, 0x6d, 0x65, 0x77, 0x6c, 0x69, 0x6e, 0x6b, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x61, 0x6c, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 })
 
 var (
@@ -138,16 +415,23 @@ func file_protos_roominfo_proto_rawDescGZIP() []byte {
 	return file_protos_roominfo_proto_rawDescData
 }
 
+var file_protos_roominfo_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
 var file_protos_roominfo_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
 var file_protos_roominfo_proto_goTypes = []any{
-	(*RoomInfo)(nil), // 0: RoomInfo
+	(RoomInfo_ContactStatus)(0), // 0: RoomInfo.ContactStatus
+	(RoomInfo_LinkStatus)(0),    // 1: RoomInfo.LinkStatus
+	(RoomInfo_ReceiptMode)(0),   // 2: RoomInfo.ReceiptMode
+	(*RoomInfo)(nil),            // 3: RoomInfo
 }
 var file_protos_roominfo_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: RoomInfo.Status:type_name -> RoomInfo.ContactStatus
+	1, // 1: RoomInfo.Link:type_name -> RoomInfo.LinkStatus
+	2, // 2: RoomInfo.Receipts:type_name -> RoomInfo.ReceiptMode
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_protos_roominfo_proto_init() }
@@ -160,13 +444,14 @@ func file_protos_roominfo_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_roominfo_proto_rawDesc), len(file_protos_roominfo_proto_rawDesc)),
-			NumEnums:      0,
+			NumEnums:      3,
 			NumMessages:   1,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_protos_roominfo_proto_goTypes,
 		DependencyIndexes: file_protos_roominfo_proto_depIdxs,
+		EnumInfos:         file_protos_roominfo_proto_enumTypes,
 		MessageInfos:      file_protos_roominfo_proto_msgTypes,
 	}.Build()
 	File_protos_roominfo_proto = out.File
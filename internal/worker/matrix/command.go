@@ -0,0 +1,357 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+桥接房间里的命令
+
+命令有两种写法，`!mewlink <命令> [参数]` 和 `!<命令> [参数]`
+第二种写法只有在命令存在的时候才会被识别，否则会当作普通消息转发
+命令的结果以 m.notice 的形式发送，命令本身不会转发到 Telegram
+*/
+
+const commandPrefix = "!mewlink"
+
+type command struct {
+	usage string
+	desc  string
+	run   func(w *MatrixWorker, ctx context.Context, index []byte, info *types.RoomInfo, args []string) string
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help": {
+			desc: "Show this help",
+			run: func(_ *MatrixWorker, _ context.Context, _ []byte, _ *types.RoomInfo, _ []string) string {
+				return commandHelp()
+			},
+		},
+		"info": {
+			desc: "Show information about this contact",
+			run:  (*MatrixWorker).cmdInfo,
+		},
+		"block": {
+			desc: "Stop forwarding messages from this contact",
+			run: func(w *MatrixWorker, _ context.Context, index []byte, _ *types.RoomInfo, _ []string) string {
+				return w.setIgnored(index, true)
+			},
+		},
+		"unblock": {
+			desc: "Forward messages from this contact again",
+			run: func(w *MatrixWorker, _ context.Context, index []byte, _ *types.RoomInfo, _ []string) string {
+				return w.setIgnored(index, false)
+			},
+		},
+		"unmute": {
+			desc: "Unmute this contact if they were muted for flooding",
+			run:  (*MatrixWorker).cmdUnmute,
+		},
+		"receipts": {
+			usage: "[on|off|default]",
+			desc:  "Show or change whether this contact sees 👀 on messages you have read",
+			run:   (*MatrixWorker).cmdReceipts,
+		},
+		"rename": {
+			usage: "<name>",
+			desc:  "Rename this room, the name will not follow Telegram anymore",
+			run:   (*MatrixWorker).cmdRename,
+		},
+		"unpin": {
+			usage: "[name|avatar]",
+			desc:  "Let the room name and avatar follow Telegram again",
+			run:   (*MatrixWorker).cmdUnpin,
+		},
+		"unlink": {
+			desc: "Unlink this room from the contact, the contact has to send /start again",
+			run:  (*MatrixWorker).cmdUnlink,
+		},
+	}
+}
+
+// 解析命令，不是命令的时候 ok 为 false
+
+func parseCommand(body string) (name string, args []string, ok bool) {
+	fields := strings.Fields(body)
+	if len(fields) == 0 {
+		return
+	}
+	if fields[0] == commandPrefix {
+		if len(fields) == 1 {
+			return "help", nil, true
+		}
+		return fields[1], fields[2:], true
+	}
+	name, found := strings.CutPrefix(fields[0], "!")
+	if !found {
+		return
+	}
+	if _, exi := commands[name]; !exi {
+		return
+	}
+	return name, fields[1:], true
+}
+
+func isCommand(body string) bool {
+	_, _, ok := parseCommand(body)
+	return ok
+}
+
+func commandHelp() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Available commands:")
+	for _, name := range names {
+		cmd := commands[name]
+		b.WriteString("\n" + commandPrefix + " " + name)
+		if cmd.usage != "" {
+			b.WriteString(" " + cmd.usage)
+		}
+		b.WriteString(" - " + cmd.desc)
+	}
+	return b.String()
+}
+
+func (w *MatrixWorker) procCommand(ctx context.Context, ev *event.Event) (index []byte) {
+	// 只有被服务的用户可以使用命令
+	if ev.Sender != id.UserID(w.Config.Get().ServedUser) {
+		return
+	}
+	name, args, _ := parseCommand(ev.Content.AsMessage().Body)
+
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(ev.RoomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		return
+	}
+	if info == nil {
+		log.Debug().
+			Str("EventID", ev.ID.String()).
+			Str("RoomID", ev.RoomID.String()).
+			Msg("Room not found")
+		return
+	}
+
+	log.Info().
+		Str("RoomID", ev.RoomID.String()).
+		Str("Command", name).
+		Strs("Args", args).
+		Msg("Command from MX")
+
+	var reply string
+	if cmd, exi := commands[name]; exi {
+		reply = cmd.run(w, ctx, index, info, args)
+	} else {
+		reply = "Unknown command: " + name + "\n" + commandHelp()
+	}
+
+	if _, err = w.Matrix.SendNotice(ctx, ev.RoomID, reply); err != nil {
+		log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+	}
+	w.setEvent(ctx, ev)
+	return
+}
+
+func (w *MatrixWorker) cmdInfo(_ context.Context, _ []byte, info *types.RoomInfo, _ []string) string {
+	username := "(none)"
+	if info.GetUsername() != "" {
+		username = "@" + info.GetUsername()
+	}
+	age := "unknown"
+	if info.GetCreatedAt() != 0 {
+		created := time.Unix(info.GetCreatedAt(), 0)
+		age = fmt.Sprintf("%s (since %s)", time.Since(created).Round(time.Minute), created.Format(time.DateTime))
+	}
+
+	muted := "not muted"
+	if time.Now().Unix() < info.GetMutedUntil() {
+		muted = time.Unix(info.GetMutedUntil(), 0).Format(time.DateTime)
+	}
+
+	return "Name: " + info.GetRoomName() +
+		"\nChatID: " + strconv.FormatInt(info.GetChatID(), 10) +
+		"\nUsername: " + username +
+		"\nLink age: " + age +
+		"\nContact status: " + info.GetStatus().String() +
+		"\nBlocked: " + strconv.FormatBool(info.GetIgnored()) +
+		"\nMuted until: " + muted +
+		"\nRead receipts: " + w.describeReceipts(info) +
+		"\nPinned name: " + strconv.FormatBool(info.GetPinRoomName()) +
+		"\nPinned avatar: " + strconv.FormatBool(info.GetPinAvatar())
+}
+
+func (w *MatrixWorker) setIgnored(index []byte, ignored bool) string {
+	_, err := w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		info.Ignored = ignored
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to update RoomInfo")
+		return "Failed to update contact: " + err.Error()
+	}
+	if ignored {
+		return "Messages from this contact will be dropped"
+	}
+	return "Messages from this contact will be forwarded again"
+}
+
+func (w *MatrixWorker) cmdUnmute(_ context.Context, index []byte, info *types.RoomInfo, _ []string) string {
+	_, err := w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		if info.GetMutedUntil() == 0 {
+			return false
+		}
+		info.MutedUntil = 0
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to update RoomInfo")
+		return "Failed to unmute: " + err.Error()
+	}
+	misc.ResetRateLimit(info.GetChatID())
+	return "Messages from this contact will be forwarded again"
+}
+
+func (w *MatrixWorker) describeReceipts(info *types.RoomInfo) string {
+	if info.GetReceipts() == types.RoomInfo_ReceiptDefault {
+		return strconv.FormatBool(w.receiptsEnabled(info)) + " (default)"
+	}
+	return strconv.FormatBool(w.receiptsEnabled(info))
+}
+
+func (w *MatrixWorker) cmdReceipts(_ context.Context, index []byte, info *types.RoomInfo, args []string) string {
+	if len(args) == 0 {
+		return "Read receipts: " + w.describeReceipts(info)
+	}
+
+	var mode types.RoomInfo_ReceiptMode
+	switch args[0] {
+	case "on":
+		mode = types.RoomInfo_ReceiptOn
+	case "off":
+		mode = types.RoomInfo_ReceiptOff
+	case "default":
+		mode = types.RoomInfo_ReceiptDefault
+	default:
+		return "Usage: " + commandPrefix + " receipts [on|off|default]"
+	}
+
+	info, err := w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		info.Receipts = mode
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to update RoomInfo")
+		return "Failed to update contact: " + err.Error()
+	}
+	return "Read receipts: " + w.describeReceipts(info)
+}
+
+// 修改房间名，同时固定房间名
+// Bot 发送的状态事件不会触发自动固定，所以要在这里固定
+
+func (w *MatrixWorker) cmdRename(ctx context.Context, index []byte, info *types.RoomInfo, args []string) string {
+	if len(args) == 0 {
+		return "Usage: " + commandPrefix + " rename " + commands["rename"].usage
+	}
+	name := strings.Join(args, " ")
+
+	_, err := w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		info.PinRoomName = true
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to update RoomInfo")
+		return "Failed to rename: " + err.Error()
+	}
+	_, err = w.Matrix.SendStateEvent(ctx, id.RoomID(info.GetRoomID()), event.StateRoomName, "", &event.RoomNameEventContent{
+		Name: name,
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to set room name")
+		return "Failed to rename: " + err.Error()
+	}
+	return "Room renamed, use `" + commandPrefix + " unpin name` to follow Telegram again"
+}
+
+// 取消固定房间名/头像，恢复成 Telegram 上的资料
+
+func (w *MatrixWorker) cmdUnpin(ctx context.Context, index []byte, _ *types.RoomInfo, args []string) string {
+	name, avatar := true, true
+	if len(args) != 0 {
+		switch args[0] {
+		case "name":
+			avatar = false
+		case "avatar":
+			name = false
+		default:
+			return "Usage: " + commandPrefix + " unpin " + commands["unpin"].usage
+		}
+	}
+
+	info, err := w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		if name {
+			info.PinRoomName = false
+		}
+		if avatar {
+			info.PinAvatar = false
+		}
+		// 下次有消息的时候立即同步资料
+		info.ProfileUpdatedAt = 0
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to unpin room profile")
+		return "Failed to unpin: " + err.Error()
+	}
+
+	// 先恢复成保存下来的资料，再从 Telegram 同步最新的资料
+	roomID := id.RoomID(info.GetRoomID())
+	if name {
+		_, err = w.Matrix.SendStateEvent(ctx, roomID, event.StateRoomName, "", &event.RoomNameEventContent{
+			Name: info.GetRoomName(),
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to restore room name")
+		}
+	}
+	if avatar {
+		_, err = w.Matrix.SendStateEvent(ctx, roomID, event.StateRoomAvatar, "", &event.RoomAvatarEventContent{
+			URL: id.ContentURIString(info.GetAvatar()),
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to restore room avatar")
+		}
+	}
+	if err = misc.UpdateProfile(ctx, w.Worker, index); err != nil {
+		log.Warn().Err(err).Msg("Failed to update profile")
+	}
+
+	return "Room profile will follow Telegram again"
+}
+
+func (w *MatrixWorker) cmdUnlink(ctx context.Context, index []byte, info *types.RoomInfo, _ []string) string {
+	if err := w.dropRoom(ctx, index, info); err != nil {
+		return "Failed to unlink: " + err.Error()
+	}
+	return "Room unlinked, you can leave this room now"
+}
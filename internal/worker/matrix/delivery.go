@@ -0,0 +1,33 @@
+package matrix
+
+import (
+	"context"
+
+	"github.com/AsenHu/mewlink/internal/config"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+)
+
+/*
+送达状态
+
+消息送达 Telegram 之后，Bot 在消息上发送已读回执，BotReceipts 为 none 的时候不发送
+没有送达的消息，Bot 在消息上回应 ⚠️，不需要翻日志也能看出哪些消息没有送达
+*/
+
+const failedReaction = "⚠️"
+
+func (w *MatrixWorker) delivered(ctx context.Context, ev *event.Event) {
+	if w.Config.Get().Matrix.BotReceipts == config.BotReceiptsNone {
+		return
+	}
+	if err := w.Matrix.SendReceipt(ctx, ev.RoomID, ev.ID, event.ReceiptTypeRead, nil); err != nil {
+		log.Warn().Err(err).Msg("Failed to send receipt")
+	}
+}
+
+func (w *MatrixWorker) notDelivered(ctx context.Context, ev *event.Event) {
+	if _, err := w.Matrix.SendReaction(ctx, ev.RoomID, ev.ID, failedReaction); err != nil {
+		log.Warn().Err(err).Msg("Failed to mark message as not delivered")
+	}
+}
@@ -0,0 +1,424 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/config"
+	"github.com/AsenHu/mewlink/internal/database"
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+管理房间里的命令
+
+管理房间不和任何联系人关联，里面的所有消息都会当作命令处理
+命令可以写成 `!<命令>`，也可以写成 `!mewlink <命令>`
+*/
+
+type managementCommand struct {
+	usage string
+	desc  string
+	run   func(w *MatrixWorker, ctx context.Context, args []string) string
+}
+
+var managementCommands map[string]managementCommand
+
+func init() {
+	managementCommands = map[string]managementCommand{
+		"help": {
+			desc: "Show this help",
+			run: func(_ *MatrixWorker, _ context.Context, _ []string) string {
+				return managementHelp()
+			},
+		},
+		"contacts": {
+			desc: "List all contacts",
+			run: func(w *MatrixWorker, _ context.Context, _ []string) string {
+				return w.listContacts("")
+			},
+		},
+		"search": {
+			usage: "<text>",
+			desc:  "Search contacts by name or username",
+			run: func(w *MatrixWorker, _ context.Context, args []string) string {
+				if len(args) == 0 {
+					return "Usage: !search " + managementCommands["search"].usage
+				}
+				return w.listContacts(strings.Join(args, " "))
+			},
+		},
+		"stats": {
+			desc: "Show bridge statistics",
+			run:  (*MatrixWorker).cmdStats,
+		},
+		"reopen": {
+			usage: "<ChatID>",
+			desc:  "Invite you into the room of a contact, creating a new one if needed",
+			run:   (*MatrixWorker).cmdReopen,
+		},
+		"pending": {
+			desc: "List contacts waiting for approval",
+			run: func(w *MatrixWorker, _ context.Context, _ []string) string {
+				return w.listPending()
+			},
+		},
+		"approve": {
+			usage: "<ChatID>",
+			desc:  "Approve a contact and create a room for them",
+			run: func(w *MatrixWorker, ctx context.Context, args []string) string {
+				return w.cmdApproval(ctx, "approve", args)
+			},
+		},
+		"deny": {
+			usage: "<ChatID>",
+			desc:  "Deny a contact, their messages will be dropped",
+			run: func(w *MatrixWorker, ctx context.Context, args []string) string {
+				return w.cmdApproval(ctx, "deny", args)
+			},
+		},
+		"allowlist": {
+			usage: "[add|remove <ChatID|@username>]",
+			desc:  "Show or change the list of users that skip approval",
+			run: func(w *MatrixWorker, _ context.Context, args []string) string {
+				return w.cmdAccessList("allowlist", database.AccessAllow, args)
+			},
+		},
+		"blocklist": {
+			usage: "[add|remove <ChatID|@username>]",
+			desc:  "Show or change the list of users whose messages are dropped",
+			run: func(w *MatrixWorker, _ context.Context, args []string) string {
+				return w.cmdAccessList("blocklist", database.AccessDeny, args)
+			},
+		},
+		"settings": {
+			desc: "Show global settings",
+			run: func(w *MatrixWorker, _ context.Context, _ []string) string {
+				return w.listSettings()
+			},
+		},
+		"set": {
+			usage: "<setting> <value>",
+			desc:  "Change a global setting",
+			run:   (*MatrixWorker).cmdSet,
+		},
+	}
+}
+
+func parseManagementCommand(body string) (name string, args []string) {
+	fields := strings.Fields(body)
+	if len(fields) != 0 && fields[0] == commandPrefix {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return "help", nil
+	}
+	return strings.TrimPrefix(fields[0], "!"), fields[1:]
+}
+
+func managementHelp() string {
+	names := make([]string, 0, len(managementCommands))
+	for name := range managementCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Available commands:")
+	for _, name := range names {
+		cmd := managementCommands[name]
+		b.WriteString("\n!" + name)
+		if cmd.usage != "" {
+			b.WriteString(" " + cmd.usage)
+		}
+		b.WriteString(" - " + cmd.desc)
+	}
+	return b.String()
+}
+
+func (w *MatrixWorker) procManagement(ctx context.Context, ev *event.Event) {
+	// 只有被服务的用户可以使用命令
+	if ev.Sender != id.UserID(w.Config.Get().ServedUser) {
+		return
+	}
+	name, args := parseManagementCommand(ev.Content.AsMessage().Body)
+
+	log.Info().
+		Str("Command", name).
+		Strs("Args", args).
+		Msg("Management command from MX")
+
+	var reply string
+	if cmd, exi := managementCommands[name]; exi {
+		reply = cmd.run(w, ctx, args)
+	} else {
+		reply = "Unknown command: " + name + "\n" + managementHelp()
+	}
+
+	if _, err := w.Matrix.SendNotice(ctx, ev.RoomID, reply); err != nil {
+		log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+	}
+	w.setEvent(ctx, ev)
+}
+
+func describeContact(info *types.RoomInfo) string {
+	line := info.GetRoomName()
+	if info.GetUsername() != "" {
+		line += " (@" + info.GetUsername() + ")"
+	}
+	line += " - ChatID " + strconv.FormatInt(info.GetChatID(), 10)
+	if info.GetStatus() != types.RoomInfo_Active {
+		line += ", " + info.GetStatus().String()
+	}
+	if info.GetLink() != types.RoomInfo_Linked {
+		line += ", " + info.GetLink().String()
+	}
+	if info.GetIgnored() {
+		line += ", blocked"
+	}
+	if time.Now().Unix() < info.GetMutedUntil() {
+		line += ", muted"
+	}
+	return line
+}
+
+// 列出联系人，query 不为空的时候只列出名字或用户名包含 query 的联系人
+
+func (w *MatrixWorker) listContacts(query string) string {
+	query = strings.ToLower(query)
+	var lines []string
+	err := w.DataBase.RoomList.ForEachRoomInfo(func(_ []byte, info *types.RoomInfo) error {
+		if query != "" &&
+			!strings.Contains(strings.ToLower(info.GetRoomName()), query) &&
+			!strings.Contains(strings.ToLower(info.GetUsername()), query) {
+			return nil
+		}
+		lines = append(lines, describeContact(info))
+		return nil
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to list contacts")
+		return "Failed to list contacts: " + err.Error()
+	}
+	if len(lines) == 0 {
+		return "No contacts found"
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("%d contact(s):\n%s", len(lines), strings.Join(lines, "\n"))
+}
+
+func (w *MatrixWorker) cmdStats(_ context.Context, _ []string) string {
+	var total, ignored int
+	status := map[types.RoomInfo_ContactStatus]int{}
+	link := map[types.RoomInfo_LinkStatus]int{}
+	err := w.DataBase.RoomList.ForEachRoomInfo(func(_ []byte, info *types.RoomInfo) error {
+		total++
+		status[info.GetStatus()]++
+		link[info.GetLink()]++
+		if info.GetIgnored() {
+			ignored++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to count contacts")
+		return "Failed to count contacts: " + err.Error()
+	}
+	events, err := w.DataBase.EventList.Count()
+	if err != nil {
+		log.Err(err).Msg("Failed to count events")
+		return "Failed to count events: " + err.Error()
+	}
+	messages, err := w.DataBase.MessageList.Count()
+	if err != nil {
+		log.Err(err).Msg("Failed to count messages")
+		return "Failed to count messages: " + err.Error()
+	}
+
+	return fmt.Sprintf("Contacts: %d\nLinked: %d, dormant: %d, archived: %d\nBlocked the bot: %d, deactivated: %d\nBlocked by you: %d\nProcessed Matrix events: %d\nBridged messages: %d",
+		total,
+		link[types.RoomInfo_Linked], link[types.RoomInfo_Dormant], link[types.RoomInfo_Archived],
+		status[types.RoomInfo_Blocked], status[types.RoomInfo_Deactivated],
+		ignored,
+		events, messages)
+}
+
+// 重新打开联系人的房间
+// 房间还在的话重新邀请，否则创建一个新的房间
+
+func (w *MatrixWorker) cmdReopen(ctx context.Context, args []string) string {
+	if len(args) != 1 {
+		return "Usage: !reopen " + managementCommands["reopen"].usage
+	}
+	chatID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "Invalid ChatID: " + args[0]
+	}
+
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByChatID(chatID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by ChatID")
+		return "Failed to find contact: " + err.Error()
+	}
+	if info == nil {
+		return "Contact not found, they have to send /start first"
+	}
+
+	if info.GetLink() == types.RoomInfo_Linked {
+		_, err = w.Matrix.InviteUser(ctx, id.RoomID(info.GetRoomID()), &mautrix.ReqInviteUser{
+			UserID: id.UserID(w.Config.Get().ServedUser),
+		})
+		if err != nil {
+			log.Err(err).Msg("Failed to invite served user")
+			return "Failed to invite you: " + err.Error()
+		}
+		return "Invited you into the room of " + info.GetRoomName()
+	}
+
+	info, err = misc.ReopenRoom(ctx, w.Worker, index)
+	if err != nil {
+		log.Err(err).Msg("Failed to reopen room")
+		return "Failed to reopen room: " + err.Error()
+	}
+	return "Created a new room for " + info.GetRoomName()
+}
+
+func (w *MatrixWorker) listSettings() string {
+	var b strings.Builder
+	b.WriteString("Settings:")
+	content := w.Config.Get()
+	for _, setting := range config.Settings {
+		b.WriteString("\n" + setting.Name + " = " + setting.Get(&content) + " - " + setting.Desc)
+	}
+	return b.String()
+}
+
+func (w *MatrixWorker) cmdSet(_ context.Context, args []string) string {
+	if len(args) < 2 {
+		return "Usage: !set " + managementCommands["set"].usage + "\n" + w.listSettings()
+	}
+	setting, ok := config.FindSetting(args[0])
+	if !ok {
+		return "Unknown setting: " + args[0] + "\n" + w.listSettings()
+	}
+	value := strings.Join(args[1:], " ")
+	err := w.Config.Update(func(content *config.Content) error {
+		return setting.Set(content, value)
+	})
+	if err != nil {
+		log.Err(err).Str("Setting", setting.Name).Msg("Failed to change setting")
+		return "Failed to change setting: " + err.Error()
+	}
+	log.Info().Str("Setting", setting.Name).Str("Value", value).Msg("Setting changed")
+	content := w.Config.Get()
+	return setting.Name + " = " + setting.Get(&content)
+}
+
+func (w *MatrixWorker) listPending() string {
+	var lines []string
+	err := w.DataBase.PendingList.ForEach(func(info *types.PendingInfo) error {
+		line := misc.UserName(info.GetFirstName(), info.GetLastName(), info.GetUsername(), info.GetChatID())
+		if info.GetUsername() != "" {
+			line += " (@" + info.GetUsername() + ")"
+		}
+		line += fmt.Sprintf(" - ChatID %d", info.GetChatID())
+		if info.GetDenied() {
+			line += ", denied"
+		} else {
+			line += fmt.Sprintf(", %d buffered message(s)", len(info.GetUpdates()))
+		}
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to list pending contacts")
+		return "Failed to list pending contacts: " + err.Error()
+	}
+	if len(lines) == 0 {
+		return "No contacts waiting for approval"
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func (w *MatrixWorker) cmdApproval(ctx context.Context, action string, args []string) string {
+	if len(args) != 1 {
+		return "Usage: !" + action + " " + managementCommands[action].usage
+	}
+	chatID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "Invalid ChatID: " + args[0]
+	}
+	return w.approve(ctx, chatID, action == "approve")
+}
+
+func (w *MatrixWorker) approve(ctx context.Context, chatID int64, approve bool) string {
+	if !approve {
+		if err := misc.DenyPending(ctx, w.Worker, chatID); err != nil {
+			log.Err(err).Int64("ChatID", chatID).Msg("Failed to deny contact")
+			return "Failed to deny: " + err.Error()
+		}
+		return fmt.Sprintf("ChatID %d denied", chatID)
+	}
+	if err := misc.ApprovePending(ctx, w.Worker, chatID); err != nil {
+		log.Err(err).Int64("ChatID", chatID).Msg("Failed to approve contact")
+		return "Failed to approve: " + err.Error()
+	}
+	return fmt.Sprintf("ChatID %d approved", chatID)
+}
+
+// 查看或者修改允许列表/屏蔽列表
+// 配置文件里的条目只能通过修改配置文件删除
+
+func (w *MatrixWorker) cmdAccessList(name string, kind database.AccessKind, args []string) string {
+	if len(args) == 0 {
+		configured := w.Config.Get().Telegram.AllowList
+		if kind == database.AccessDeny {
+			configured = w.Config.Get().Telegram.DenyList
+		}
+		var lines []string
+		for _, entry := range configured {
+			lines = append(lines, entry+" (config)")
+		}
+		lines = append(lines, w.DataBase.AccessList.List(kind)...)
+		if len(lines) == 0 {
+			return "The " + name + " is empty"
+		}
+		return strings.Join(lines, "\n")
+	}
+	if len(args) != 2 {
+		return "Usage: !" + name + " " + managementCommands[name].usage
+	}
+
+	switch args[0] {
+	case "add":
+		if err := w.DataBase.AccessList.Add(kind, args[1]); err != nil {
+			log.Err(err).Str("Entry", args[1]).Msg("Failed to add entry")
+			return "Failed to add: " + err.Error()
+		}
+		log.Info().Str("List", name).Str("Entry", args[1]).Msg("Entry added")
+		return args[1] + " added to the " + name
+	case "remove":
+		removed, err := w.DataBase.AccessList.Remove(kind, args[1])
+		if err != nil {
+			log.Err(err).Str("Entry", args[1]).Msg("Failed to remove entry")
+			return "Failed to remove: " + err.Error()
+		}
+		if !removed {
+			return args[1] + " is not in the " + name
+		}
+		log.Info().Str("List", name).Str("Entry", args[1]).Msg("Entry removed")
+		return args[1] + " removed from the " + name
+	default:
+		return "Usage: !" + name + " " + managementCommands[name].usage
+	}
+}
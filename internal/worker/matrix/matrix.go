@@ -22,7 +22,8 @@ func (w MatrixWorker) FromMatrix(_ context.Context, ev *event.Event) {
 		defer w.WaitGroup.Done()
 		// log.Debug().Str("EventID", ev.ID.String()).Msg("Received message from Matrix")
 		// 检查消息是否是被服务的用户发送的
-		if ev.Sender != id.UserID(w.Config.Content.ServedUser) {
+		// 房间里的机器人和集成发送的 m.notice 也会转发，除非开启了 SuppressNotices
+		if ev.Sender != id.UserID(w.Config.Get().ServedUser) && !w.isIntegrationNotice(ev) {
 			log.Debug().Str("EventID", ev.ID.String()).Msg("Message not sent by served user")
 			return
 		}
@@ -40,37 +41,67 @@ func (w MatrixWorker) FromMatrix(_ context.Context, ev *event.Event) {
 			return
 		}
 
+		// 之前的图片还在等待合并的时候，先把图片发送出去
+		if ev.Content.AsMessage().MsgType != event.MsgImage {
+			w.flushPendingImages(ev.RoomID)
+		}
+
 		// 确定消息类型，然后调用相应的处理函数
-		// 1. 如果是普通消息，调用 `procText`
-		// 2. 如果是其他消息，直接返回
+		// 1. 如果是管理房间里的消息，调用 `procManagement`
+		// 2. 如果是贴纸，调用 `procUnsupported` 提示不能转发
+		// 3. 如果是命令，调用 `procCommand`
+		// 4. 如果是普通消息、m.emote 或者 m.notice，调用 `procText`
+		// 5. 如果是图片，调用 `procImage`，连续发送的图片会被收集起来一起发送
+		// 6. 如果是位置，调用 `procLocation`
+		// 7. 如果是 vCard 文件，调用 `procContact`
+		// 8. 如果是其他消息，调用 `procUnsupported` 提示不能转发
 		var index []byte
-		switch ev.Content.AsMessage().MsgType {
-		case event.MsgText:
+		switch {
+		case ev.RoomID == w.Config.Get().Matrix.ManagementRoom:
+			if ev.Content.AsMessage().MsgType == event.MsgText {
+				w.procManagement(w.Context, ev)
+			}
+			return
+		case ev.Type == event.EventSticker:
+			index = w.procUnsupported(w.Context, ev)
+		case ev.Content.AsMessage().MsgType == event.MsgText && isCommand(ev.Content.AsMessage().Body):
+			index = w.procCommand(w.Context, ev)
+		case ev.Content.AsMessage().MsgType == event.MsgText,
+			ev.Content.AsMessage().MsgType == event.MsgEmote,
+			ev.Content.AsMessage().MsgType == event.MsgNotice:
 			index = w.procText(w.Context, ev)
+		case ev.Content.AsMessage().MsgType == event.MsgImage:
+			index = w.procImage(w.Context, ev)
+		case ev.Content.AsMessage().MsgType == event.MsgLocation:
+			index = w.procLocation(w.Context, ev)
+		case isVCard(ev.Content.AsMessage()):
+			index = w.procContact(w.Context, ev)
 		default:
-			if w.Config.Content.LogLevel == zerolog.DebugLevel {
+			if w.Config.Get().LogLevel == zerolog.DebugLevel {
 				jsonEvent, _ := json.Marshal(ev)
 				log.Debug().
 					Str("Event", string(jsonEvent)).
 					Msg("Unsupported message type")
 			}
-			return
+			index = w.procUnsupported(w.Context, ev)
 		}
 
 		// 杂项操作
-
-		// 发送已读回执
-		if err = w.Matrix.SendReceipt(w.Context, ev.RoomID, ev.ID, "m.read", nil); err != nil {
-			log.Warn().Err(err).Msg("Failed to send receipt")
-		}
-
 		// 更新房间信息
-		if err = misc.UpdateProfile(w.Context, index, w.Matrix); err != nil {
+		if err = misc.UpdateProfile(w.Context, w.Worker, index); err != nil {
 			log.Warn().Err(err).Msg("Failed to update profile")
 		}
 	}()
 }
 
+// 桥接房间里其他成员发送的 m.notice，Bot 自己发送的提示不算
+
+func (w *MatrixWorker) isIntegrationNotice(ev *event.Event) bool {
+	cfg := w.Config.Get().Matrix
+	return ev.Content.AsMessage().MsgType == event.MsgNotice && !cfg.SuppressNotices &&
+		ev.Sender != w.Matrix.UserID && ev.RoomID != cfg.ManagementRoom
+}
+
 func (w *MatrixWorker) sendErrToMatrix(ctx context.Context, roomID id.RoomID, err error) {
 	message := err.Error() + "\nAn error occurred, please check the logs"
 	_, err = w.Matrix.SendText(ctx, roomID, message)
@@ -78,3 +109,12 @@ func (w *MatrixWorker) sendErrToMatrix(ctx context.Context, roomID id.RoomID, er
 		log.Error().Err(err).Msg("Failed to send message to Matrix")
 	}
 }
+
+// 记录消息已经处理过，避免重复处理
+
+func (w *MatrixWorker) setEvent(ctx context.Context, ev *event.Event) {
+	if err := w.DataBase.EventList.Set(ev.ID); err != nil {
+		log.Err(err).Msg("Failed to set event")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+	}
+}
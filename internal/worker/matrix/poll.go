@@ -0,0 +1,210 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf16"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+投票（MSC3381）
+
+被服务的用户在 Matrix 上发起的投票转发到 Telegram，结束投票的时候在 Telegram 上也结束投票
+Bot 不能代替被服务的用户在 Telegram 上投票，所以 poll.response 不会被转发
+*/
+
+// Telegram 的限制
+const (
+	maxPollQuestionLength = 300
+	maxPollOptionLength   = 100
+	minPollOptions        = 2
+	maxPollOptions        = 10
+)
+
+func (w MatrixWorker) FromMatrixPoll(_ context.Context, ev *event.Event) {
+	w.WaitGroup.Add(1)
+	go func() {
+		defer w.WaitGroup.Done()
+		if ev.Sender != id.UserID(w.Config.Get().ServedUser) {
+			return
+		}
+
+		// 检查事件是否处理过
+		exi, err := w.DataBase.EventList.IsExi(ev.ID)
+		if err != nil {
+			log.Err(err).Msg("Failed to check if event exists")
+			return
+		}
+		if exi {
+			return
+		}
+		// 之前的图片还在等待合并的时候，先把图片发送出去
+		w.flushPendingImages(ev.RoomID)
+
+		var index []byte
+		switch ev.Type {
+		case event.EventUnstablePollStart:
+			index = w.procPollStart(w.Context, ev)
+		case misc.EventUnstablePollEnd:
+			index = w.procPollEnd(w.Context, ev)
+		default:
+			log.Debug().Str("EventID", ev.ID.String()).Msg("Poll response can not be bridged")
+			w.setEvent(w.Context, ev)
+			return
+		}
+
+		// 更新房间信息
+		if err = misc.UpdateProfile(w.Context, w.Worker, index); err != nil {
+			log.Warn().Err(err).Msg("Failed to update profile")
+		}
+	}()
+}
+
+// Telegram 的长度限制按照 UTF-16 计算，截断的时候不能截断代理对
+
+func truncate(text string, limit int) string {
+	if misc.UTF16Len(text) <= limit {
+		return text
+	}
+	units := utf16.Encode([]rune(text))[:limit-1]
+	if last := rune(units[len(units)-1]); utf16.IsSurrogate(last) && last < 0xDC00 {
+		units = units[:len(units)-1]
+	}
+	return string(utf16.Decode(units)) + "…"
+}
+
+func (w *MatrixWorker) procPollStart(ctx context.Context, ev *event.Event) (index []byte) {
+	content, ok := ev.Content.Parsed.(*event.PollStartEventContent)
+	if !ok {
+		log.Warn().Str("EventID", ev.ID.String()).Msg("Invalid poll")
+		return
+	}
+
+	index, info := w.getContact(ctx, ev)
+	if info == nil {
+		return
+	}
+
+	start := content.PollStart
+	answers := start.Answers
+	if len(answers) < minPollOptions || len(answers) > maxPollOptions {
+		err := fmt.Errorf("Telegram polls need %d to %d options, this poll has %d", minPollOptions, maxPollOptions, len(answers))
+		log.Warn().Str("EventID", ev.ID.String()).Int("Options", len(answers)).Msg("Poll not supported by Telegram")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		w.notDelivered(ctx, ev)
+		w.setEvent(ctx, ev)
+		return
+	}
+
+	question := start.Question.Text
+	if question == "" && len(start.Question.Message) != 0 {
+		question = start.Question.Message[0].Body
+	}
+	params := &bot.SendPollParams{
+		ChatID:                info.GetChatID(),
+		Question:              truncate(question, maxPollQuestionLength),
+		IsAnonymous:           new(bool),
+		AllowsMultipleAnswers: start.MaxSelections > 1,
+	}
+	threadID, reply := w.threadReply(ev, info.GetChatID())
+	params.ReplyParameters = reply
+	answerIDs := make([]string, 0, len(answers))
+	for _, answer := range answers {
+		text := answer.Text
+		if text == "" && len(answer.Message) != 0 {
+			text = answer.Message[0].Body
+		}
+		params.Options = append(params.Options, models.InputPollOption{Text: truncate(text, maxPollOptionLength)})
+		answerIDs = append(answerIDs, answer.ID)
+	}
+
+	log.Info().
+		Str("SendTo", info.GetRoomName()).
+		Str("Question", question).
+		Msg("Poll from MX")
+
+	msg, err := w.Outbox.SendPoll(ctx, params)
+	if w.handleSendErr(ctx, ev, index, err) {
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, info.GetChatID(), []int{msg.ID}, ev.RoomID, ev.ID, threadID)
+
+	err = w.DataBase.PollList.Put(&types.PollInfo{
+		PollID:     msg.Poll.ID,
+		ChatID:     info.GetChatID(),
+		MessageID:  int64(msg.ID),
+		RoomID:     ev.RoomID.String(),
+		EventID:    ev.ID.String(),
+		AnswerIDs:  answerIDs,
+		CreatedAt:  time.Now().Unix(),
+		FromMatrix: true,
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to save poll")
+	}
+
+	// 保存消息
+	w.setEvent(ctx, ev)
+	w.delivered(ctx, ev)
+	return
+}
+
+func (w *MatrixWorker) procPollEnd(ctx context.Context, ev *event.Event) (index []byte) {
+	defer w.setEvent(ctx, ev)
+
+	relatesTo, _ := ev.Content.Raw["m.relates_to"].(map[string]any)
+	startID, _ := relatesTo["event_id"].(string)
+	pollInfo, err := w.DataBase.PollList.GetByEventID(id.EventID(startID))
+	if err != nil {
+		log.Err(err).Msg("Failed to get poll")
+		return
+	}
+	if pollInfo == nil || pollInfo.GetRoomID() != ev.RoomID.String() {
+		return
+	}
+
+	// 联系人发起的投票只能由联系人结束
+	if !pollInfo.GetFromMatrix() {
+		if _, err = w.Matrix.SendNotice(ctx, ev.RoomID, "This poll was created on Telegram, only the contact can end it there"); err != nil {
+			log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+		}
+		w.notDelivered(ctx, ev)
+		return
+	}
+
+	closed, err := w.DataBase.PollList.Close(pollInfo.GetPollID())
+	if err != nil {
+		log.Err(err).Msg("Failed to close poll")
+		return
+	}
+	if !closed {
+		return
+	}
+
+	log.Info().
+		Int64("ChatID", pollInfo.GetChatID()).
+		Str("EventID", startID).
+		Msg("Poll ended from MX")
+
+	_, err = w.Outbox.StopPoll(ctx, &bot.StopPollParams{
+		ChatID:    pollInfo.GetChatID(),
+		MessageID: int(pollInfo.GetMessageID()),
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to stop poll on Telegram")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		w.notDelivered(ctx, ev)
+		return
+	}
+	w.delivered(ctx, ev)
+	return
+}
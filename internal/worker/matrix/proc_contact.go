@@ -0,0 +1,98 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+)
+
+// Telegram 的 vCard 最多 2048 字节，超过的时候只发送名字和电话号码
+const maxVCardLength = 2048
+
+// 判断 m.file 是否是 vCard
+
+func isVCard(content *event.MessageEventContent) bool {
+	if content.MsgType != event.MsgFile {
+		return false
+	}
+	if content.Info != nil {
+		switch strings.ToLower(content.Info.MimeType) {
+		case "text/vcard", "text/x-vcard", "text/directory":
+			return true
+		}
+	}
+	return strings.HasSuffix(strings.ToLower(content.GetFileName()), ".vcf")
+}
+
+// Matrix 上的 .vcf 文件转发成 Telegram 的联系人卡片
+
+func (w *MatrixWorker) procContact(ctx context.Context, ev *event.Event) (index []byte) {
+	index, info := w.getContact(ctx, ev)
+	if info == nil {
+		return
+	}
+
+	content := ev.Content.AsMessage()
+	uri, err := content.URL.Parse()
+	if err != nil {
+		log.Warn().Err(err).Str("EventID", ev.ID.String()).Msg("Invalid vCard URL")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		w.notDelivered(ctx, ev)
+		w.setEvent(ctx, ev)
+		return
+	}
+	data, err := w.Matrix.DownloadBytes(ctx, uri)
+	if err != nil {
+		log.Err(err).Msg("Failed to download vCard from Matrix")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		w.notDelivered(ctx, ev)
+		return
+	}
+
+	contact := misc.ParseVCard(string(data))
+	// Telegram 的联系人必须有名字和电话号码
+	if contact.PhoneNumber == "" {
+		err = fmt.Errorf("the vCard has no phone number, Telegram can not send it as a contact")
+		log.Warn().Str("EventID", ev.ID.String()).Msg("vCard without phone number")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		w.notDelivered(ctx, ev)
+		w.setEvent(ctx, ev)
+		return
+	}
+	if contact.FirstName == "" {
+		contact.FirstName = contact.PhoneNumber
+	}
+	vCard := string(data)
+	if len(vCard) > maxVCardLength {
+		vCard = ""
+	}
+
+	log.Info().
+		Str("SendTo", info.GetRoomName()).
+		Str("Contact", strings.TrimSpace(contact.FirstName+" "+contact.LastName)).
+		Msg("Contact from MX")
+
+	threadID, reply := w.threadReply(ev, info.GetChatID())
+	msg, err := w.Outbox.SendContact(ctx, &bot.SendContactParams{
+		ChatID:          info.GetChatID(),
+		PhoneNumber:     contact.PhoneNumber,
+		FirstName:       contact.FirstName,
+		LastName:        contact.LastName,
+		VCard:           vCard,
+		ReplyParameters: reply,
+	})
+	if w.handleSendErr(ctx, ev, index, err) {
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, info.GetChatID(), []int{msg.ID}, ev.RoomID, ev.ID, threadID)
+
+	// 保存消息
+	w.setEvent(ctx, ev)
+	w.delivered(ctx, ev)
+	return
+}
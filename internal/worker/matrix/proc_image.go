@@ -0,0 +1,261 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+图片
+
+被服务的用户连续发送的图片按照房间收集起来，ImageBatchWindow 毫秒内没有新的图片之后一起发送
+只有一张图片的时候使用 sendPhoto，多张图片使用 sendMediaGroup 以相册的形式发送
+图片的说明文字（MSC2530）会作为 Telegram 上每张图片的说明文字
+超过 Telegram 长度限制的说明文字会被截断，剩下的部分回复图片作为文字发送
+不同讨论串里的图片不会被合并
+同一个房间里的其他消息要等收集到的图片发送之后再发送，见 flushPendingImages
+*/
+
+// Telegram 的相册最多有 10 张图片
+const maxMediaGroup = 10
+
+type imageBatch struct {
+	threadID id.EventID
+	events   []*event.Event
+	timer    *time.Timer
+	// 发送完之后关闭
+	done chan struct{}
+}
+
+type imageCollector struct {
+	mutex   sync.Mutex
+	batches map[id.RoomID]*imageBatch
+}
+
+var images = imageCollector{batches: map[id.RoomID]*imageBatch{}}
+
+func (w *MatrixWorker) procImage(_ context.Context, ev *event.Event) (index []byte) {
+	// 图片发送之后再更新房间信息
+	w.collectImage(ev)
+	return
+}
+
+// 把图片放进收集器，每收到一张图片都重新计时，收集满一个相册的时候马上发送
+// 图片在另一个讨论串里的时候，之前的图片马上发送
+
+func (w *MatrixWorker) collectImage(ev *event.Event) {
+	window := time.Duration(w.Config.Get().Matrix.ImageBatchWindow) * time.Millisecond
+
+	images.mutex.Lock()
+	defer images.mutex.Unlock()
+
+	// Stop 返回 false 说明这一批图片已经开始发送了，需要新的一批
+	threadID := threadRoot(ev)
+	if b := images.batches[ev.RoomID]; b != nil && b.timer.Stop() {
+		if b.threadID == threadID {
+			if !slices.ContainsFunc(b.events, func(e *event.Event) bool { return e.ID == ev.ID }) {
+				b.events = append(b.events, ev)
+			}
+			if len(b.events) < maxMediaGroup {
+				b.timer.Reset(window)
+				return
+			}
+			go w.flushImages(ev.RoomID, b)
+			return
+		}
+		go w.flushImages(ev.RoomID, b)
+	}
+
+	b := &imageBatch{threadID: threadID, events: []*event.Event{ev}, done: make(chan struct{})}
+	images.batches[ev.RoomID] = b
+	w.WaitGroup.Add(1)
+	b.timer = time.AfterFunc(window, func() {
+		w.flushImages(ev.RoomID, b)
+	})
+}
+
+// 发送一批图片，发送完之前这批图片会留在收集器里，其他消息可以等它发送完
+
+func (w *MatrixWorker) flushImages(roomID id.RoomID, b *imageBatch) {
+	defer w.WaitGroup.Done()
+	defer func() {
+		images.mutex.Lock()
+		if images.batches[roomID] == b {
+			delete(images.batches, roomID)
+		}
+		images.mutex.Unlock()
+		close(b.done)
+	}()
+
+	index := w.procImages(w.Context, b.events)
+
+	// 更新房间信息
+	if err := misc.UpdateProfile(w.Context, w.Worker, index); err != nil {
+		log.Warn().Err(err).Msg("Failed to update profile")
+	}
+}
+
+// 房间里还有收集到的图片的时候马上发送，正在发送的时候等它发送完
+// 其他消息在这之后发送，避免 "图片，然后文字" 在 Telegram 上变成 "文字，然后图片"
+
+func (w *MatrixWorker) flushPendingImages(roomID id.RoomID) {
+	images.mutex.Lock()
+	b := images.batches[roomID]
+	if b == nil {
+		images.mutex.Unlock()
+		return
+	}
+	if b.timer.Stop() {
+		images.mutex.Unlock()
+		w.flushImages(roomID, b)
+		return
+	}
+	images.mutex.Unlock()
+	<-b.done
+}
+
+func (w *MatrixWorker) procImages(ctx context.Context, events []*event.Event) (index []byte) {
+	index, info := w.getContact(ctx, events[0])
+	if info == nil {
+		// 联系人不可用，这一批图片都不会再投递
+		if index != nil {
+			for _, ev := range events[1:] {
+				w.notDelivered(ctx, ev)
+				w.setEvent(ctx, ev)
+			}
+		}
+		return
+	}
+
+	// 下载和上传需要一些时间，让联系人知道正在发送图片
+	w.sendChatAction(ctx, info.GetChatID(), models.ChatActionUploadPhoto)
+
+	// 下载图片，下载失败的图片单独报错
+	var sent []*event.Event
+	var media []models.InputMedia
+	// 超过长度限制的说明文字，剩下的部分在图片之后回复图片发送
+	var rests []misc.TextChunk
+	for _, ev := range events {
+		content := ev.Content.AsMessage()
+		data, err := w.downloadImage(ctx, content)
+		if err != nil {
+			log.Err(err).Str("EventID", ev.ID.String()).Msg("Failed to download image from Matrix")
+			w.sendErrToMatrix(ctx, ev.RoomID, err)
+			w.notDelivered(ctx, ev)
+			continue
+		}
+		var caption, rest misc.TextChunk
+		if content.FileName != "" && content.Body != content.FileName {
+			caption, rest = misc.SplitCaption(content.Body, nil)
+		}
+		// 带有 MSC3725 内容警告的图片在 Telegram 上标记为 spoiler
+		_, spoiler := ev.Content.Raw[misc.ContentWarningKey]
+		sent = append(sent, ev)
+		rests = append(rests, rest)
+		media = append(media, &models.InputMediaPhoto{
+			Media:           fmt.Sprintf("attach://image%d", len(media)),
+			Caption:         caption.Text,
+			HasSpoiler:      spoiler,
+			MediaAttachment: bytes.NewReader(data),
+		})
+	}
+	if len(sent) == 0 {
+		return
+	}
+
+	log.Info().
+		Str("SendTo", info.GetRoomName()).
+		Int("Count", len(sent)).
+		Msg("Images from MX")
+
+	threadID, reply := w.threadReply(sent[0], info.GetChatID())
+	var messageIDs []int
+	var err error
+	if len(media) == 1 {
+		photo := media[0].(*models.InputMediaPhoto)
+		var msg *models.Message
+		msg, err = w.Outbox.SendPhoto(ctx, &bot.SendPhotoParams{
+			ChatID:          info.GetChatID(),
+			Photo:           &models.InputFileUpload{Filename: "image", Data: photo.MediaAttachment},
+			Caption:         photo.Caption,
+			HasSpoiler:      photo.HasSpoiler,
+			ReplyParameters: reply,
+		})
+		if err == nil {
+			messageIDs = []int{msg.ID}
+		}
+	} else {
+		var msgs []*models.Message
+		msgs, err = w.Outbox.SendMediaGroup(ctx, &bot.SendMediaGroupParams{
+			ChatID:          info.GetChatID(),
+			Media:           media,
+			ReplyParameters: reply,
+		})
+		for _, msg := range msgs {
+			messageIDs = append(messageIDs, msg.ID)
+		}
+	}
+	if w.handleSendErr(ctx, sent[0], index, err) {
+		_, unreachable := misc.ContactStatusFromErr(err)
+		for _, ev := range sent[1:] {
+			w.notDelivered(ctx, ev)
+			if unreachable {
+				w.setEvent(ctx, ev)
+			}
+		}
+		return
+	}
+
+	// 保存消息，相册里的每张图片对应一个事件，说明文字剩下的部分也对应到这个事件
+	for i, ev := range sent {
+		if i < len(messageIDs) {
+			ids := []int{messageIDs[i]}
+			if rests[i].Text != "" {
+				ids = append(ids, w.sendCaptionRest(ctx, info.GetChatID(), ev, rests[i], messageIDs[i])...)
+			}
+			misc.SaveThreadMessage(w.Worker, info.GetChatID(), ids, ev.RoomID, ev.ID, threadID)
+		}
+		w.setEvent(ctx, ev)
+	}
+	// 已读回执会把之前的图片一起标记为已读
+	w.delivered(ctx, sent[len(sent)-1])
+	return
+}
+
+// 发送说明文字超过长度限制的部分，回复图片所在的消息
+// 发送失败的时候只报错，图片已经发送过了
+
+func (w *MatrixWorker) sendCaptionRest(ctx context.Context, chatID int64, ev *event.Event, rest misc.TextChunk, messageID int) (messageIDs []int) {
+	messageIDs, err := w.sendText(ctx, chatID, rest.Text, rest.Entities, false, &models.ReplyParameters{
+		MessageID:                messageID,
+		AllowSendingWithoutReply: true,
+	})
+	if err != nil {
+		log.Err(err).Str("EventID", ev.ID.String()).Msg("Failed to send the rest of the caption to Telegram")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+	}
+	return
+}
+
+func (w *MatrixWorker) downloadImage(ctx context.Context, content *event.MessageEventContent) (data []byte, err error) {
+	if content.URL == "" {
+		err = fmt.Errorf("encrypted images are not supported")
+		return
+	}
+	uri, err := content.URL.Parse()
+	if err != nil {
+		return
+	}
+	return w.Matrix.DownloadBytes(ctx, uri)
+}
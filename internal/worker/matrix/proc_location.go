@@ -0,0 +1,91 @@
+package matrix
+
+import (
+	"context"
+	"strings"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+)
+
+// Matrix 的 m.location 转发成 Telegram 的位置，带描述的位置转发成地点
+
+func (w *MatrixWorker) procLocation(ctx context.Context, ev *event.Event) (index []byte) {
+	index, info := w.getContact(ctx, ev)
+	if info == nil {
+		return
+	}
+
+	geoURI, description := locationFromEvent(ev)
+	latitude, longitude, err := misc.ParseGeoURI(geoURI)
+	if err != nil {
+		log.Warn().Err(err).Str("EventID", ev.ID.String()).Msg("Invalid location")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		w.notDelivered(ctx, ev)
+		w.setEvent(ctx, ev)
+		return
+	}
+
+	log.Info().
+		Str("SendTo", info.GetRoomName()).
+		Str("Location", geoURI).
+		Msg("Location from MX")
+
+	threadID, reply := w.threadReply(ev, info.GetChatID())
+	var msg *models.Message
+	if description != "" {
+		msg, err = w.Outbox.SendVenue(ctx, &bot.SendVenueParams{
+			ChatID:          info.GetChatID(),
+			Latitude:        latitude,
+			Longitude:       longitude,
+			Title:           description,
+			Address:         locationAddress(ev, description),
+			ReplyParameters: reply,
+		})
+	} else {
+		msg, err = w.Outbox.SendLocation(ctx, &bot.SendLocationParams{
+			ChatID:          info.GetChatID(),
+			Latitude:        latitude,
+			Longitude:       longitude,
+			ReplyParameters: reply,
+		})
+	}
+	if w.handleSendErr(ctx, ev, index, err) {
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, info.GetChatID(), []int{msg.ID}, ev.RoomID, ev.ID, threadID)
+
+	// 保存消息
+	w.setEvent(ctx, ev)
+	w.delivered(ctx, ev)
+	return
+}
+
+// 地点的地址，body 不是 geo URI 的时候使用 body，否则使用描述
+// 很多客户端的 body 只是 "Location at geo:..."，不能让联系人看到 geo URI
+
+func locationAddress(ev *event.Event, description string) string {
+	body := ev.Content.AsMessage().Body
+	if body == "" || strings.Contains(body, "geo:") {
+		return description
+	}
+	return body
+}
+
+// 优先使用 MSC3488 的字段，旧的客户端只有 geo_uri
+
+func locationFromEvent(ev *event.Event) (geoURI, description string) {
+	geoURI = ev.Content.AsMessage().GeoURI
+	location, ok := ev.Content.Raw["org.matrix.msc3488.location"].(map[string]any)
+	if !ok {
+		return
+	}
+	if uri, ok := location["uri"].(string); ok && uri != "" {
+		geoURI = uri
+	}
+	description, _ = location["description"].(string)
+	return
+}
@@ -0,0 +1,108 @@
+package matrix
+
+import (
+	"context"
+
+	"github.com/AsenHu/mewlink/internal/config"
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/go-telegram/bot"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// 被服务的用户离开或者拒绝了桥接房间的邀请
+// 根据 LeavePolicy 决定怎么处理这个联系人
+
+func (w *MatrixWorker) procMember(ctx context.Context, ev *event.Event) {
+	servedUser := id.UserID(w.Config.Get().ServedUser)
+	if ev.GetStateKey() != servedUser.String() {
+		return
+	}
+	membership := ev.Content.AsMember().Membership
+	if membership != event.MembershipLeave && membership != event.MembershipBan {
+		return
+	}
+
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(ev.RoomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
+		return
+	}
+	if info == nil || info.GetLink() != types.RoomInfo_Linked {
+		return
+	}
+
+	policy := w.Config.Get().Matrix.LeavePolicy
+	log.Info().
+		Str("RoomID", ev.RoomID.String()).
+		Int64("ChatID", info.GetChatID()).
+		Str("Membership", string(membership)).
+		Str("Policy", policy).
+		Msg("Served user left the room")
+
+	switch policy {
+	case config.LeavePolicyReinvite:
+		_, err = w.Matrix.InviteUser(ctx, ev.RoomID, &mautrix.ReqInviteUser{UserID: servedUser})
+		if err != nil {
+			log.Err(err).Msg("Failed to reinvite served user")
+		}
+	case config.LeavePolicyArchive:
+		_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+			info.Link = types.RoomInfo_Archived
+			return true
+		})
+		if err != nil {
+			log.Err(err).Msg("Failed to archive room")
+			return
+		}
+		w.notifyContact(ctx, info.GetChatID(), "This chat has been archived, your messages will not be delivered")
+		w.leaveRoom(ctx, ev.RoomID)
+	case config.LeavePolicyDrop:
+		if err = w.dropRoom(ctx, index, info); err != nil {
+			return
+		}
+		w.leaveRoom(ctx, ev.RoomID)
+	default:
+		// 下次收到联系人的消息时再重新创建房间
+		_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+			info.Link = types.RoomInfo_Dormant
+			return true
+		})
+		if err != nil {
+			log.Err(err).Msg("Failed to mark room as dormant")
+		}
+	}
+}
+
+// 删除联系人和房间的关联，并通知联系人
+
+func (w *MatrixWorker) dropRoom(ctx context.Context, index []byte, info *types.RoomInfo) (err error) {
+	if err = w.DataBase.RoomList.DeleteRoomInfoByIndex(index); err != nil {
+		log.Err(err).Msg("Failed to delete room")
+		return
+	}
+	log.Info().
+		Int64("ChatID", info.GetChatID()).
+		Str("RoomID", info.GetRoomID()).
+		Msg("Room unlinked")
+	w.notifyContact(ctx, info.GetChatID(), "This chat has been closed, please resend `/start` if you want to talk again")
+	return
+}
+
+func (w *MatrixWorker) notifyContact(ctx context.Context, chatID int64, text string) {
+	_, err := w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Telegram")
+	}
+}
+
+func (w *MatrixWorker) leaveRoom(ctx context.Context, roomID id.RoomID) {
+	if _, err := w.Matrix.LeaveRoom(ctx, roomID); err != nil {
+		log.Warn().Err(err).Msg("Failed to leave room")
+	}
+}
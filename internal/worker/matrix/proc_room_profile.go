@@ -0,0 +1,55 @@
+package matrix
+
+import (
+	"context"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// 被服务的用户自己修改了房间名或者头像，固定下来，之后同步资料的时候就不会覆盖
+// RoomName 和 Avatar 仍然保存 Telegram 上的资料，取消固定的时候用来恢复
+
+func (w *MatrixWorker) procRoomProfile(ctx context.Context, ev *event.Event) {
+	if ev.Sender != id.UserID(w.Config.Get().ServedUser) {
+		return
+	}
+
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(ev.RoomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
+		return
+	}
+	if info == nil {
+		return
+	}
+
+	_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		switch ev.Type {
+		case event.StateRoomName:
+			if info.GetPinRoomName() {
+				return false
+			}
+			info.PinRoomName = true
+		case event.StateRoomAvatar:
+			if info.GetPinAvatar() {
+				return false
+			}
+			info.PinAvatar = true
+		default:
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to pin room profile")
+		return
+	}
+
+	log.Info().
+		Int64("ChatID", info.GetChatID()).
+		Str("Type", ev.Type.Type).
+		Msg("Room profile pinned")
+}
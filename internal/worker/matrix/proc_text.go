@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
+	"strings"
 
+	"github.com/AsenHu/mewlink/internal/types"
 	"github.com/AsenHu/mewlink/internal/worker/misc"
 	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog/log"
 	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
 )
 
 func (w *MatrixWorker) procText(ctx context.Context, ev *event.Event) (index []byte) {
@@ -19,33 +22,84 @@ func (w *MatrixWorker) procText(ctx context.Context, ev *event.Event) (index []b
 		return
 	}
 
-	// 获取房间信息的 index
-	// 上读锁定 RoomID
-	roomLock, _ := w.DataBase.RoomList.RoomIDMutex.LoadOrStore(ev.RoomID, &sync.RWMutex{})
-	roomLock.(*sync.RWMutex).RLock()
-	index, err := w.DataBase.RoomList.GetIndexByRoomID(ev.RoomID)
-	roomLock.(*sync.RWMutex).RUnlock()
-	if err != nil {
-		log.Err(err).Msg("Failed to get index by RoomID")
-		w.sendErrToMatrix(ctx, ev.RoomID, err)
+	msgType := ev.Content.AsMessage().MsgType
+	if msgType == event.MsgNotice && w.Config.Get().Matrix.SuppressNotices {
+		log.Debug().Str("EventID", ev.ID.String()).Msg("Notice suppressed")
+		w.setEvent(ctx, ev)
 		return
 	}
-	if index == nil {
-		log.Debug().
-			Str("EventID", ev.ID.String()).
-			Str("RoomID", ev.RoomID.String()).
-			Msg("Room not found")
+
+	index, info := w.getContact(ctx, ev)
+	if info == nil {
+		return
+	}
+
+	log.Info().
+		Str("SendTo", info.GetRoomName()).
+		Str("Type", string(msgType)).
+		Str("Msg", ev.Content.AsMessage().Body).
+		Msg("Msg from MX")
+
+	// 带 spoiler 的消息使用 formatted_body，其他格式只保留文字
+	text := ev.Content.AsMessage().Body
+	var entities []models.MessageEntity
+	if content := ev.Content.AsMessage(); content.Format == event.FormatHTML && strings.Contains(content.FormattedBody, "data-mx-spoiler") {
+		text, entities = misc.HTMLToSpoilers(content.FormattedBody)
+	}
+
+	// m.emote 显示成斜体的 "* 名字 动作"
+	// m.notice 静默发送，不会提醒联系人
+	if msgType == event.MsgEmote {
+		prefix := "* " + w.displayName(ctx, ev.Sender) + " "
+		for i := range entities {
+			entities[i].Offset += misc.UTF16Len(prefix)
+		}
+		text = prefix + text
+		entities = append(entities, models.MessageEntity{
+			Type:   models.MessageEntityTypeItalic,
+			Length: misc.UTF16Len(text),
+		})
+	}
+	// 机器人和集成发送的 m.notice 前面加上发送者的名字，避免看起来像是被服务的用户写的
+	if ev.Sender != id.UserID(w.Config.Get().ServedUser) {
+		prefix := w.displayName(ctx, ev.Sender) + ": "
+		for i := range entities {
+			entities[i].Offset += misc.UTF16Len(prefix)
+		}
+		text = prefix + text
+	}
+
+	// 转发消息到 Telegram
+	threadID, reply := w.threadReply(ev, info.GetChatID())
+	messageIDs, err := w.sendText(ctx, info.GetChatID(), text, entities, msgType == event.MsgNotice, reply)
+	misc.SaveThreadMessage(w.Worker, info.GetChatID(), messageIDs, ev.RoomID, ev.ID, threadID)
+	if w.handleSendErr(ctx, ev, index, err) {
 		return
 	}
 
+	// 保存消息
+	w.setEvent(ctx, ev)
+	w.delivered(ctx, ev)
+
+	return
+}
+
+// 获取消息要发送给的联系人
+// 联系人不存在或者不可用的时候 info 为 nil，需要的提示已经发送过了
+
+func (w *MatrixWorker) getContact(ctx context.Context, ev *event.Event) (index []byte, info *types.RoomInfo) {
 	// 获取房间信息
-	indexLock, _ := w.DataBase.RoomList.RoomInfoBucket.IndexMutex.LoadOrStore(string(index), &sync.RWMutex{})
-	indexLock.(*sync.RWMutex).RLock()
-	info, err := w.DataBase.RoomList.GetRoomInfoByIndex(index)
-	indexLock.(*sync.RWMutex).RUnlock()
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(ev.RoomID)
 	if err != nil {
-		log.Err(err).Msg("Failed to get RoomInfo by index")
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
 		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		return nil, nil
+	}
+	if info == nil {
+		log.Debug().
+			Str("EventID", ev.ID.String()).
+			Str("RoomID", ev.RoomID.String()).
+			Msg("Room not found")
 		return
 	}
 
@@ -61,30 +115,100 @@ func (w *MatrixWorker) procText(ctx context.Context, ev *event.Event) (index []b
 			Msg("RoomInfo not valid, this should not happen, database corrupted")
 		w.sendErrToMatrix(ctx, ev.RoomID, err)
 		w.StopProc()
-		return
+		return nil, nil
 	}
 
-	log.Info().
-		Str("SendTo", info.GetRoomName()).
-		Str("Msg", ev.Content.AsMessage().Body).
-		Msg("Msg from MX")
+	// 联系人屏蔽了 Bot 或者注销了账号，不再尝试投递
+	if info.GetStatus() != types.RoomInfo_Active {
+		log.Info().
+			Str("SendTo", info.GetRoomName()).
+			Str("Status", info.GetStatus().String()).
+			Msg("Contact unreachable, message dropped")
+		if _, err = w.Matrix.SendNotice(ctx, ev.RoomID, "Message not delivered, this contact is unreachable on Telegram"); err != nil {
+			log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+		}
+		w.notDelivered(ctx, ev)
+		w.setEvent(ctx, ev)
+		return index, nil
+	}
+	return
+}
 
-	// 转发消息到 Telegram
-	_, err = w.Telegram.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: info.ChatID,
-		Text:   ev.Content.AsMessage().Body,
-	})
+// 处理发送到 Telegram 的错误，返回 true 代表出错了
+
+func (w *MatrixWorker) handleSendErr(ctx context.Context, ev *event.Event, index []byte, err error) bool {
+	if err != nil {
+		w.notDelivered(ctx, ev)
+	}
+	if status, ok := misc.ContactStatusFromErr(err); ok {
+		log.Warn().Err(err).Msg("Contact unreachable")
+		if _, err = misc.SetContactStatus(ctx, w.Worker, index, status); err != nil {
+			log.Err(err).Msg("Failed to set contact status")
+		}
+		w.setEvent(ctx, ev)
+		return true
+	}
 	if err != nil {
 		log.Err(err).Msg("Failed to send message to Telegram")
 		w.sendErrToMatrix(ctx, ev.RoomID, err)
-		return
+		return true
 	}
+	return false
+}
 
-	// 保存消息
-	if err = w.DataBase.EventList.Set(ev.ID); err != nil {
-		log.Err(err).Msg("Failed to set event")
-		w.sendErrToMatrix(ctx, ev.RoomID, err)
+// 发送文本到 Telegram
+// 超过长度限制的文本会被拆分成多条消息，超过 DocumentThreshold 的文本会以 .txt 文件发送
+// 部分消息发送失败的时候，也会返回已经发送的消息 ID
+// silent 为 true 的时候不会提醒联系人，reply 不为 nil 的时候第一条消息会回复这条消息
+
+func (w *MatrixWorker) sendText(ctx context.Context, chatID int64, text string, entities []models.MessageEntity, silent bool, reply *models.ReplyParameters) (messageIDs []int, err error) {
+	threshold := w.Config.Get().Telegram.DocumentThreshold
+	if threshold != 0 && int64(misc.UTF16Len(text)) > threshold {
+		w.sendChatAction(ctx, chatID, models.ChatActionUploadDocument)
+		var msg *models.Message
+		msg, err = w.Outbox.SendDocument(ctx, &bot.SendDocumentParams{
+			ChatID: chatID,
+			Document: &models.InputFileUpload{
+				Filename: "message.txt",
+				Data:     strings.NewReader(text),
+			},
+			Caption:             "This message is too long, so it was sent as a file",
+			DisableNotification: silent,
+			ReplyParameters:     reply,
+		})
+		if err != nil {
+			return
+		}
+		return []int{msg.ID}, nil
 	}
 
+	for _, chunk := range misc.SplitText(text, entities, misc.MaxTextLength) {
+		var msg *models.Message
+		msg, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:              chatID,
+			Text:                chunk.Text,
+			Entities:            chunk.Entities,
+			DisableNotification: silent,
+			ReplyParameters:     reply,
+		})
+		if err != nil {
+			return
+		}
+		messageIDs = append(messageIDs, msg.ID)
+		reply = nil
+	}
 	return
 }
+
+// 获取 Matrix 用户的显示名，获取失败的时候使用 localpart
+
+func (w *MatrixWorker) displayName(ctx context.Context, userID id.UserID) string {
+	resp, err := w.Matrix.GetDisplayName(ctx, userID)
+	if err != nil || resp.DisplayName == "" {
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to get display name")
+		}
+		return userID.Localpart()
+	}
+	return resp.DisplayName
+}
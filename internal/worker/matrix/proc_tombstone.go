@@ -0,0 +1,92 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// 桥接房间升级了，跟着去新的房间
+
+func (w *MatrixWorker) procTombstone(ctx context.Context, ev *event.Event) {
+	newRoomID := ev.Content.AsTombstone().ReplacementRoom
+	if newRoomID == "" {
+		return
+	}
+
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(ev.RoomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
+		return
+	}
+	if info == nil {
+		return
+	}
+
+	// 先加入新的房间，失败的话继续使用旧的房间
+	via := tombstoneVia(ev, newRoomID)
+	_, err = w.Matrix.JoinRoom(ctx, newRoomID.String(), &mautrix.ReqJoinRoom{
+		Via: via,
+	})
+	if err != nil {
+		log.Err(err).
+			Str("RoomID", ev.RoomID.String()).
+			Str("NewRoomID", newRoomID.String()).
+			Strs("Via", via).
+			Msg("Failed to join replacement room")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		// 联系人还关联在旧的房间上，被服务的用户在别的地方看不到这个错误
+		misc.Alert(ctx, w.Worker, fmt.Sprintf(
+			"Failed to follow the upgrade of room %s to %s (via %s): %s\n"+
+				"Messages are still bridged to the old room, please invite the bot to the new room",
+			ev.RoomID, newRoomID, strings.Join(via, ", "), err))
+		return
+	}
+
+	// RoomInfo 和 RoomID index 要一起修改
+	info, err = w.DataBase.RoomList.MoveRoomByIndex(index, func(info *types.RoomInfo) (id.RoomID, error) {
+		if id.RoomID(info.GetRoomID()) != ev.RoomID {
+			return "", nil
+		}
+		return newRoomID, nil
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to move room")
+		w.sendErrToMatrix(ctx, ev.RoomID, err)
+		return
+	}
+
+	log.Info().
+		Int64("ChatID", info.GetChatID()).
+		Str("OldRoomID", ev.RoomID.String()).
+		Str("RoomID", info.GetRoomID()).
+		Msg("Room upgraded")
+}
+
+// 加入新的房间时使用的服务器
+// 发送升级的用户的服务器一定在新的房间里，新旧房间 ID 里的服务器也很可能在
+
+func tombstoneVia(ev *event.Event, newRoomID id.RoomID) (via []string) {
+	candidates := []string{ev.Sender.Homeserver(), roomServer(newRoomID), roomServer(ev.RoomID)}
+	for _, server := range candidates {
+		if server != "" && !slices.Contains(via, server) {
+			via = append(via, server)
+		}
+	}
+	return
+}
+
+// 房间 ID 里的服务器，新版本的房间 ID 没有服务器
+
+func roomServer(roomID id.RoomID) string {
+	_, server, _ := strings.Cut(string(roomID), ":")
+	return server
+}
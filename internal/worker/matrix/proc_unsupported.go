@@ -0,0 +1,44 @@
+package matrix
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+)
+
+// 不能转发到 Telegram 的消息，在房间里提示被服务的用户，并记录事件，避免重复处理
+
+func (w *MatrixWorker) procUnsupported(ctx context.Context, ev *event.Event) (index []byte) {
+	defer w.setEvent(ctx, ev)
+
+	// 只提示已经桥接的房间
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(ev.RoomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
+		return nil
+	}
+	if info == nil {
+		return
+	}
+
+	msgType := string(ev.Content.AsMessage().MsgType)
+	if msgType == "" {
+		msgType = ev.Type.Type
+	}
+	log.Info().
+		Str("SendTo", info.GetRoomName()).
+		Str("Type", msgType).
+		Msg("Unsupported message from MX")
+	w.notDelivered(ctx, ev)
+
+	notice := w.Config.Get().Matrix.UnsupportedNotice
+	if notice == "" {
+		return
+	}
+	if _, err = w.Matrix.SendNotice(ctx, ev.RoomID, strings.ReplaceAll(notice, "{type}", msgType)); err != nil {
+		log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+	}
+	return
+}
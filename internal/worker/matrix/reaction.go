@@ -0,0 +1,69 @@
+package matrix
+
+import (
+	"context"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// 处理回应
+// 目前只有管理房间里审批提示上的回应有意义
+
+func (w MatrixWorker) FromMatrixReaction(_ context.Context, ev *event.Event) {
+	w.WaitGroup.Add(1)
+	go func() {
+		defer w.WaitGroup.Done()
+		if ev.Sender != id.UserID(w.Config.Get().ServedUser) {
+			return
+		}
+
+		// 检查事件是否处理过
+		exi, err := w.DataBase.EventList.IsExi(ev.ID)
+		if err != nil {
+			log.Err(err).Msg("Failed to check if event exists")
+			return
+		}
+		if exi {
+			return
+		}
+
+		if ev.RoomID == w.Config.Get().Matrix.ManagementRoom {
+			w.procApprovalReaction(w.Context, ev)
+		}
+
+		if err = w.DataBase.EventList.Set(ev.ID); err != nil {
+			log.Err(err).Msg("Failed to set event")
+		}
+	}()
+}
+
+func (w *MatrixWorker) procApprovalReaction(ctx context.Context, ev *event.Event) {
+	relatesTo := ev.Content.AsReaction().RelatesTo
+
+	var approve bool
+	switch relatesTo.Key {
+	case "👍", "👍️", "✅", "✔️":
+		approve = true
+	case "👎", "👎️", "❌", "✖️":
+		approve = false
+	default:
+		return
+	}
+
+	chatID, err := misc.FindPendingByNotice(w.Worker, relatesTo.EventID)
+	if err != nil {
+		log.Err(err).Msg("Failed to find pending contact")
+		return
+	}
+	if chatID == 0 {
+		return
+	}
+
+	reply := w.approve(ctx, chatID, approve)
+	if _, err = w.Matrix.SendNotice(ctx, ev.RoomID, reply); err != nil {
+		log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+	}
+}
@@ -0,0 +1,122 @@
+package matrix
+
+import (
+	"context"
+	"slices"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+已读回执
+
+被服务的用户在桥接房间里发送 m.read 的时候，在联系人最后一条消息上加一个 👀
+Telegram 私聊里的消息 ID 是按顺序增加的，只有已读的位置不早于联系人最后一条消息的时候才会标记
+每条消息只标记一次，私密的已读回执（m.read.private）不会转发
+*/
+
+const seenReaction = "👀"
+
+func (w MatrixWorker) FromMatrixReceipt(_ context.Context, ev *event.Event) {
+	if ev.RoomID == w.Config.Get().Matrix.ManagementRoom {
+		return
+	}
+
+	// 找到被服务的用户已读的事件
+	servedUser := id.UserID(w.Config.Get().ServedUser)
+	var eventID id.EventID
+	for evID, receipts := range *ev.Content.AsReceipt() {
+		if _, ok := receipts[event.ReceiptTypeRead][servedUser]; ok {
+			eventID = evID
+			break
+		}
+	}
+	if eventID == "" {
+		return
+	}
+
+	w.WaitGroup.Add(1)
+	go func() {
+		defer w.WaitGroup.Done()
+		w.procReceipt(w.Context, ev.RoomID, eventID)
+	}()
+}
+
+func (w *MatrixWorker) procReceipt(ctx context.Context, roomID id.RoomID, eventID id.EventID) {
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(roomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
+		return
+	}
+	if info == nil || !w.receiptsEnabled(info) ||
+		info.GetLink() != types.RoomInfo_Linked || info.GetStatus() != types.RoomInfo_Active {
+		return
+	}
+	last := info.GetLastMessageID()
+	if last <= info.GetSeenMessageID() {
+		return
+	}
+
+	// 已读的位置要不早于联系人最后一条消息
+	mapping, err := w.DataBase.MessageList.GetByEventID(eventID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get message mapping")
+		return
+	}
+	if mapping == nil || mapping.GetChatID() != info.GetChatID() ||
+		len(mapping.GetMessageIDs()) == 0 || slices.Max(mapping.GetMessageIDs()) < last {
+		return
+	}
+
+	// 先占住这条消息，避免同时收到多个回执的时候重复标记
+	claimed := false
+	_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		if last <= info.GetSeenMessageID() {
+			return false
+		}
+		info.SeenMessageID = last
+		claimed = true
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to update RoomInfo")
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	log.Debug().
+		Int64("ChatID", info.GetChatID()).
+		Int64("MessageID", last).
+		Msg("Read receipt from MX")
+
+	_, err = w.Outbox.SetMessageReaction(ctx, &bot.SetMessageReactionParams{
+		ChatID:    info.GetChatID(),
+		MessageID: int(last),
+		Reaction: []models.ReactionType{{
+			Type:              models.ReactionTypeTypeEmoji,
+			ReactionTypeEmoji: &models.ReactionTypeEmoji{Emoji: seenReaction},
+		}},
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to mark message as seen on Telegram")
+	}
+}
+
+// 房间里的设置优先于全局设置
+
+func (w *MatrixWorker) receiptsEnabled(info *types.RoomInfo) bool {
+	switch info.GetReceipts() {
+	case types.RoomInfo_ReceiptOn:
+		return true
+	case types.RoomInfo_ReceiptOff:
+		return false
+	}
+	return w.Config.Get().Matrix.ReadReceipts
+}
@@ -0,0 +1,43 @@
+package matrix
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+)
+
+// 处理房间的状态事件
+// 和消息一样，同步的时候可能会收到处理过的事件，所以也要记录下来
+
+func (w MatrixWorker) FromMatrixState(_ context.Context, ev *event.Event) {
+	w.WaitGroup.Add(1)
+	go func() {
+		defer w.WaitGroup.Done()
+		// 检查事件是否处理过
+		exi, err := w.DataBase.EventList.IsExi(ev.ID)
+		if err != nil {
+			log.Err(err).Msg("Failed to check if event exists")
+			return
+		}
+		if exi {
+			log.Debug().Str("EventID", ev.ID.String()).Msg("Event already exists")
+			return
+		}
+
+		switch ev.Type {
+		case event.StateMember:
+			w.procMember(w.Context, ev)
+		case event.StateTombstone:
+			w.procTombstone(w.Context, ev)
+		case event.StateRoomName, event.StateRoomAvatar:
+			w.procRoomProfile(w.Context, ev)
+		default:
+			return
+		}
+
+		if err = w.DataBase.EventList.Set(ev.ID); err != nil {
+			log.Err(err).Msg("Failed to set event")
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package matrix
+
+import (
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+讨论串
+
+Telegram 没有讨论串，讨论串里的消息在 Telegram 上作为对讨论串根消息的回复发送
+讨论串的根事件会和消息的对应关系保存在一起，联系人回复这些消息的时候会回到同一个讨论串里
+*/
+
+// 事件所在的讨论串，直接读取 m.relates_to，所有类型的事件都可以使用
+
+func threadRoot(ev *event.Event) id.EventID {
+	relatesTo, _ := ev.Content.Raw["m.relates_to"].(map[string]any)
+	if relType, _ := relatesTo["rel_type"].(string); relType != string(event.RelThread) {
+		return ""
+	}
+	root, _ := relatesTo["event_id"].(string)
+	return id.EventID(root)
+}
+
+// 讨论串里的消息要回复的 Telegram 消息，根事件没有转发到 Telegram 的时候 reply 为 nil
+
+func (w *MatrixWorker) threadReply(ev *event.Event, chatID int64) (threadID id.EventID, reply *models.ReplyParameters) {
+	threadID = threadRoot(ev)
+	if threadID == "" {
+		return
+	}
+	mapping, err := w.DataBase.MessageList.GetByEventID(threadID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get message mapping")
+		return
+	}
+	if mapping == nil || mapping.GetChatID() != chatID || len(mapping.GetMessageIDs()) == 0 {
+		return
+	}
+	reply = &models.ReplyParameters{
+		MessageID:                int(mapping.GetMessageIDs()[0]),
+		AllowSendingWithoutReply: true,
+	}
+	return
+}
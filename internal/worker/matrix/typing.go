@@ -0,0 +1,115 @@
+package matrix
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+正在输入
+
+被服务的用户在房间里输入的时候，每隔 typingInterval 向联系人发送一次 typing
+Telegram 上的聊天动作 5 秒之后就会消失，所以需要一直发送，直到 m.typing 里没有被服务的用户
+停止同步之后不会再收到 m.typing，所以停止同步的时候也会停止，最多发送 maxTypingDuration
+*/
+
+const typingInterval = 5 * time.Second
+
+// 客户端发送 m.typing 时一般使用的超时时间，超过这个时间还在输入的话客户端会重新发送
+const maxTypingDuration = 30 * time.Second
+
+type typingRooms struct {
+	mutex sync.Mutex
+	rooms map[id.RoomID]*typingRoom
+}
+
+type typingRoom struct {
+	cancel context.CancelFunc
+}
+
+var typing = typingRooms{rooms: map[id.RoomID]*typingRoom{}}
+
+func (w MatrixWorker) FromMatrixTyping(_ context.Context, ev *event.Event) {
+	if ev.RoomID == w.Config.Get().Matrix.ManagementRoom {
+		return
+	}
+	isTyping := slices.Contains(ev.Content.AsTyping().UserIDs, id.UserID(w.Config.Get().ServedUser))
+
+	typing.mutex.Lock()
+	defer typing.mutex.Unlock()
+
+	room, exi := typing.rooms[ev.RoomID]
+	if !isTyping {
+		if exi {
+			room.cancel()
+			delete(typing.rooms, ev.RoomID)
+		}
+		return
+	}
+	if exi {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(w.Context, maxTypingDuration)
+	stopSync := context.AfterFunc(w.SyncContext, cancel)
+	room = &typingRoom{cancel: cancel}
+	typing.rooms[ev.RoomID] = room
+	w.WaitGroup.Add(1)
+	go func() {
+		defer w.WaitGroup.Done()
+		defer stopSync()
+		w.keepTyping(ctx, ev.RoomID)
+
+		// 超时之后也要删除，下一次 m.typing 才能重新开始
+		cancel()
+		typing.mutex.Lock()
+		if typing.rooms[ev.RoomID] == room {
+			delete(typing.rooms, ev.RoomID)
+		}
+		typing.mutex.Unlock()
+	}()
+}
+
+func (w *MatrixWorker) keepTyping(ctx context.Context, roomID id.RoomID) {
+	_, info, err := w.DataBase.RoomList.GetRoomInfoByRoomID(roomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by RoomID")
+		return
+	}
+	// 只有能收到消息的联系人需要知道
+	if info == nil || info.GetLink() != types.RoomInfo_Linked || info.GetStatus() != types.RoomInfo_Active {
+		return
+	}
+
+	ticker := time.NewTicker(typingInterval)
+	defer ticker.Stop()
+	for {
+		w.sendChatAction(ctx, info.GetChatID(), models.ChatActionTyping)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 发送聊天动作，失败了也不影响消息的发送
+
+func (w *MatrixWorker) sendChatAction(ctx context.Context, chatID int64, action models.ChatAction) {
+	_, err := w.Outbox.SendChatAction(ctx, &bot.SendChatActionParams{
+		ChatID: chatID,
+		Action: action,
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Debug().Err(err).Int64("ChatID", chatID).Str("Action", string(action)).Msg("Failed to send chat action")
+	}
+}
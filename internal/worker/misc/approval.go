@@ -0,0 +1,124 @@
+package misc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+联系人审批
+
+审批可以通过管理房间里的命令，也可以通过审批提示上的回应
+通过审批的时候要拿着联系人的审批锁，直到缓存的消息都转发完，这期间联系人发来的新消息会等待
+创建房间和转发消息要在 Telegram Worker 里处理，通过 Worker.ProcApproved 调用
+*/
+
+// 锁定联系人的审批，返回解锁的函数
+
+func LockApproval(w *worker.Worker, chatID int64) (unlock func()) {
+	lock, _ := w.DataBase.PendingList.ChatIDMutex.LoadOrStore(chatID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// 通过审批，创建房间并按顺序转发缓存的消息
+
+func ApprovePending(ctx context.Context, w *worker.Worker, chatID int64) (err error) {
+	unlock := LockApproval(w, chatID)
+	defer unlock()
+
+	pending, err := w.DataBase.PendingList.Get(chatID)
+	if err != nil {
+		return
+	}
+	// 被拒绝的联系人也可以重新通过审批
+	if pending == nil {
+		return fmt.Errorf("ChatID %d is not waiting for approval", chatID)
+	}
+	log.Info().Int64("ChatID", chatID).Msg("Contact approved")
+
+	// 按照联系人发送 `/start` 的方式创建房间
+	start := &models.Update{
+		Message: &models.Message{
+			Chat: models.Chat{
+				ID:        chatID,
+				Type:      models.ChatTypePrivate,
+				FirstName: pending.GetFirstName(),
+				LastName:  pending.GetLastName(),
+				Username:  pending.GetUsername(),
+			},
+			Text: "/start",
+		},
+	}
+	if index := w.ProcApproved(ctx, start); index == nil {
+		return fmt.Errorf("failed to create room for ChatID %d", chatID)
+	}
+
+	// 先删除等待列表，转发失败的时候也不会重复转发
+	if err = w.DataBase.PendingList.Delete(chatID); err != nil {
+		return
+	}
+	for _, raw := range pending.GetUpdates() {
+		var update models.Update
+		if err := json.Unmarshal(raw, &update); err != nil {
+			log.Err(err).Msg("Failed to unmarshal buffered update")
+			continue
+		}
+		w.ProcApproved(ctx, &update)
+	}
+	return
+}
+
+// 拒绝审批，回复联系人，之后的消息都会被丢弃
+
+func DenyPending(ctx context.Context, w *worker.Worker, chatID int64) (err error) {
+	found := false
+	_, err = w.DataBase.PendingList.Update(chatID, func(info *types.PendingInfo) bool {
+		if info.GetChatID() == 0 || info.GetDenied() {
+			return false
+		}
+		found = true
+		info.Denied = true
+		info.Updates = nil
+		return true
+	})
+	if err != nil {
+		return
+	}
+	if !found {
+		return fmt.Errorf("ChatID %d is not waiting for approval", chatID)
+	}
+
+	log.Info().Int64("ChatID", chatID).Msg("Contact denied")
+
+	reply := w.Config.Get().Telegram.DenyReply
+	if reply == "" {
+		return
+	}
+	_, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   reply,
+	})
+	return
+}
+
+// 通过管理房间里的审批提示找到对应的联系人
+
+func FindPendingByNotice(w *worker.Worker, eventID id.EventID) (chatID int64, err error) {
+	err = w.DataBase.PendingList.ForEach(func(info *types.PendingInfo) error {
+		if info.GetNoticeEventID() == eventID.String() && !info.GetDenied() {
+			chatID = info.GetChatID()
+		}
+		return nil
+	})
+	return
+}
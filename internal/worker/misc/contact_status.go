@@ -0,0 +1,62 @@
+package misc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/go-telegram/bot"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/id"
+)
+
+// 从 Telegram 返回的错误中判断联系人是否屏蔽了 Bot 或者注销了账号
+// 403 的描述一般是 "Forbidden: bot was blocked by the user" 或者 "Forbidden: user is deactivated"
+
+func ContactStatusFromErr(err error) (status types.RoomInfo_ContactStatus, ok bool) {
+	if !errors.Is(err, bot.ErrorForbidden) {
+		return types.RoomInfo_Active, false
+	}
+	if strings.Contains(err.Error(), "deactivated") {
+		return types.RoomInfo_Deactivated, true
+	}
+	return types.RoomInfo_Blocked, true
+}
+
+// 更新联系人的状态
+// 如果状态发生了变化，会在 Matrix 房间里发送一条提示
+
+func SetContactStatus(ctx context.Context, w *worker.Worker, index []byte, status types.RoomInfo_ContactStatus) (changed bool, err error) {
+	info, err := w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		if info.GetStatus() == status {
+			return false
+		}
+		info.Status = status
+		changed = true
+		return true
+	})
+	if err != nil || info == nil || !changed {
+		return
+	}
+
+	log.Info().
+		Int64("ChatID", info.GetChatID()).
+		Str("Status", status.String()).
+		Msg("Contact status changed")
+
+	var notice string
+	switch status {
+	case types.RoomInfo_Blocked:
+		notice = "This contact blocked the bot, messages will not be delivered until they send /start again"
+	case types.RoomInfo_Deactivated:
+		notice = "This contact's Telegram account is deactivated, messages will not be delivered"
+	default:
+		notice = "This contact is reachable again, messages will be delivered"
+	}
+	if _, err := w.Matrix.SendNotice(ctx, id.RoomID(info.GetRoomID()), notice); err != nil {
+		log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+	}
+	return
+}
@@ -0,0 +1,179 @@
+package misc
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-telegram/bot/models"
+	"maunium.net/go/mautrix/format"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+Telegram 格式和 Matrix HTML 之间的转换
+
+目前只转换 Telegram 和 Matrix 都有的 spoiler，以及 Matrix 没有的自定义表情
+自定义表情显示成替代的 emoji，有图片的时候显示成行内的图片
+*/
+
+// go-telegram/bot 没有定义 spoiler
+const MessageEntityTypeSpoiler models.MessageEntityType = "spoiler"
+
+// 自定义表情在 Matrix 上显示的高度
+const customEmojiHeight = "32"
+
+// 把 spoiler 和自定义表情转换成 Matrix 的 HTML，没有需要转换的格式时 ok 为 false
+// emojis 是自定义表情的 ID 和图片，没有图片的自定义表情只显示替代的 emoji
+
+func EntitiesToHTML(text string, entities []models.MessageEntity, emojis map[string]id.ContentURIString) (formatted string, ok bool) {
+	units := utf16.Encode([]rune(text))
+	var used []models.MessageEntity
+	for _, entity := range entities {
+		if entity.Type != MessageEntityTypeSpoiler && entity.Type != models.MessageEntityTypeCustomEmoji {
+			continue
+		}
+		entity.Offset = min(max(entity.Offset, 0), len(units))
+		entity.Length = min(max(entity.Length, 0), len(units)-entity.Offset)
+		if entity.Length != 0 {
+			used = append(used, entity)
+		}
+	}
+	if len(used) == 0 {
+		return
+	}
+
+	sortEntities(used)
+	return renderEntities(units, 0, len(units), used, emojis), true
+}
+
+// 按照位置排序，位置相同的时候外层的格式在前面
+
+func sortEntities(entities []models.MessageEntity) {
+	slices.SortStableFunc(entities, func(a, b models.MessageEntity) int {
+		if a.Offset != b.Offset {
+			return a.Offset - b.Offset
+		}
+		return b.Length - a.Length
+	})
+}
+
+func renderEntities(units []uint16, start, end int, entities []models.MessageEntity, emojis map[string]id.ContentURIString) string {
+	var b strings.Builder
+	pos := start
+	for len(entities) != 0 {
+		entity := entities[0]
+		entityEnd := entity.Offset + entity.Length
+		// 在这个格式里面的格式，超出范围的 spoiler 在这个格式后面继续，其他格式截掉
+		var children, rest []models.MessageEntity
+		for _, child := range entities[1:] {
+			if child.Offset >= entityEnd {
+				rest = append(rest, child)
+				continue
+			}
+			if childEnd := child.Offset + child.Length; childEnd > entityEnd {
+				if child.Type == MessageEntityTypeSpoiler {
+					tail := child
+					tail.Offset, tail.Length = entityEnd, childEnd-entityEnd
+					rest = append(rest, tail)
+				}
+				child.Length = entityEnd - child.Offset
+			}
+			children = append(children, child)
+		}
+		sortEntities(rest)
+
+		b.WriteString(escapeUnits(units[pos:entity.Offset]))
+		inner := renderEntities(units, entity.Offset, entityEnd, children, emojis)
+		switch entity.Type {
+		case MessageEntityTypeSpoiler:
+			b.WriteString("<span data-mx-spoiler>" + inner + "</span>")
+		case models.MessageEntityTypeCustomEmoji:
+			uri, exi := emojis[entity.CustomEmojiID]
+			if !exi {
+				b.WriteString(inner)
+				break
+			}
+			alt := html.EscapeString(string(utf16.Decode(units[entity.Offset:entityEnd])))
+			b.WriteString(`<img data-mx-emoticon src="` + html.EscapeString(string(uri)) +
+				`" alt="` + alt + `" title="` + alt + `" height="` + customEmojiHeight + `">`)
+		}
+		pos = entityEnd
+		entities = rest
+	}
+	b.WriteString(escapeUnits(units[pos:end]))
+	return b.String()
+}
+
+func escapeUnits(units []uint16) string {
+	return strings.ReplaceAll(html.EscapeString(string(utf16.Decode(units))), "\n", "<br>")
+}
+
+// spoiler 的开始和结束位置，用私有区的字符标记，解析完 HTML 之后再去掉
+const (
+	spoilerStart = '\uE000'
+	spoilerEnd   = '\uE001'
+)
+
+var spoilerParser = &format.HTMLParser{
+	TabsToSpaces: 4,
+	Newline:      "\n",
+	// 只保留 spoiler，其他格式只保留文字
+	BoldConverter:          func(text string, _ format.Context) string { return text },
+	ItalicConverter:        func(text string, _ format.Context) string { return text },
+	StrikethroughConverter: func(text string, _ format.Context) string { return text },
+	UnderlineConverter:     func(text string, _ format.Context) string { return text },
+	MonospaceConverter:     func(text string, _ format.Context) string { return text },
+	SpoilerConverter: func(text, _ string, _ format.Context) string {
+		return string(spoilerStart) + text + string(spoilerEnd)
+	},
+}
+
+// 回复里引用的原来的消息，这个版本的 HTMLParser 不会去掉
+var replyFallback = regexp.MustCompile(`(?is)<mx-reply>.*?</mx-reply>`)
+
+// 把 Matrix HTML 里的 data-mx-spoiler 转换成 Telegram 的 spoiler，其他格式只保留文字
+
+func HTMLToSpoilers(formatted string) (text string, entities []models.MessageEntity) {
+	formatted = replyFallback.ReplaceAllString(formatted, "")
+	parsed := spoilerParser.Parse(formatted, format.NewContext(context.Background()))
+
+	var b strings.Builder
+	var starts []int
+	offset := 0
+	for _, r := range parsed {
+		switch r {
+		case spoilerStart:
+			starts = append(starts, offset)
+		case spoilerEnd:
+			if len(starts) == 0 {
+				continue
+			}
+			start := starts[len(starts)-1]
+			starts = starts[:len(starts)-1]
+			if offset > start {
+				entities = append(entities, models.MessageEntity{
+					Type:   MessageEntityTypeSpoiler,
+					Offset: start,
+					Length: offset - start,
+				})
+			}
+		default:
+			b.WriteRune(r)
+			offset += utf16.RuneLen(r)
+		}
+	}
+	// 嵌套的 spoiler 里面的先结束，排序之后外层的在前面
+	sortEntities(entities)
+	return b.String(), entities
+}
+
+// MSC3725 的内容警告，spoiler 的图片在支持的客户端上会被模糊
+
+const (
+	ContentWarningKey     = "town.robin.msc3725.content_warning"
+	ContentWarningSpoiler = "town.robin.msc3725.spoiler"
+)
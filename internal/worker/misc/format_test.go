@@ -0,0 +1,147 @@
+package misc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"maunium.net/go/mautrix/id"
+)
+
+func spoiler(offset, length int) models.MessageEntity {
+	return models.MessageEntity{Type: MessageEntityTypeSpoiler, Offset: offset, Length: length}
+}
+
+func customEmoji(offset, length int, emojiID string) models.MessageEntity {
+	return models.MessageEntity{Type: models.MessageEntityTypeCustomEmoji, Offset: offset, Length: length, CustomEmojiID: emojiID}
+}
+
+func TestEntitiesToHTML(t *testing.T) {
+	emojis := map[string]id.ContentURIString{"cat": "mxc://example.com/cat"}
+	tests := []struct {
+		name     string
+		text     string
+		entities []models.MessageEntity
+		want     string
+		wantOK   bool
+	}{
+		{
+			name:     "没有需要转换的格式",
+			text:     "plain",
+			entities: []models.MessageEntity{{Type: models.MessageEntityTypeBold, Length: 5}},
+		},
+		{
+			name:     "代理对之后的 spoiler",
+			text:     "😀 secret",
+			entities: []models.MessageEntity{spoiler(3, 6)},
+			want:     "😀 <span data-mx-spoiler>secret</span>",
+			wantOK:   true,
+		},
+		{
+			name:     "转义 HTML 和换行",
+			text:     "a<b>\nc",
+			entities: []models.MessageEntity{spoiler(0, 6)},
+			want:     "<span data-mx-spoiler>a&lt;b&gt;<br>c</span>",
+			wantOK:   true,
+		},
+		{
+			name:     "spoiler 里的自定义表情",
+			text:     "a 😺 b",
+			entities: []models.MessageEntity{spoiler(0, 6), customEmoji(2, 2, "cat")},
+			want:     `<span data-mx-spoiler>a <img data-mx-emoticon src="mxc://example.com/cat" alt="😺" title="😺" height="32"> b</span>`,
+			wantOK:   true,
+		},
+		{
+			name:     "没有图片的自定义表情只显示 emoji",
+			text:     "😺!",
+			entities: []models.MessageEntity{customEmoji(0, 2, "dog")},
+			want:     "😺!",
+			wantOK:   true,
+		},
+		{
+			name:     "格式的顺序不影响嵌套",
+			text:     "abcdef",
+			entities: []models.MessageEntity{spoiler(2, 2), spoiler(0, 6)},
+			want:     "<span data-mx-spoiler>ab<span data-mx-spoiler>cd</span>ef</span>",
+			wantOK:   true,
+		},
+		{
+			name:     "重叠的 spoiler 在外层结束之后继续",
+			text:     "abcdef",
+			entities: []models.MessageEntity{spoiler(0, 4), spoiler(2, 4)},
+			want:     "<span data-mx-spoiler>ab<span data-mx-spoiler>cd</span></span><span data-mx-spoiler>ef</span>",
+			wantOK:   true,
+		},
+		{
+			name:     "超出文字的格式被截掉",
+			text:     "ab",
+			entities: []models.MessageEntity{spoiler(1, 10), spoiler(5, 1)},
+			want:     "a<span data-mx-spoiler>b</span>",
+			wantOK:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := EntitiesToHTML(tt.text, tt.entities, emojis)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("EntitiesToHTML(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestHTMLToSpoilers(t *testing.T) {
+	tests := []struct {
+		name         string
+		formatted    string
+		wantText     string
+		wantEntities []models.MessageEntity
+	}{
+		{
+			name:         "代理对之后的 spoiler",
+			formatted:    `😀 <span data-mx-spoiler>secret</span>`,
+			wantText:     "😀 secret",
+			wantEntities: []models.MessageEntity{spoiler(3, 6)},
+		},
+		{
+			name:         "嵌套的 spoiler",
+			formatted:    `<span data-mx-spoiler>a <span data-mx-spoiler="reason">b</span> c</span>`,
+			wantText:     "a b c",
+			wantEntities: []models.MessageEntity{spoiler(0, 5), spoiler(2, 1)},
+		},
+		{
+			name:      "其他格式只保留文字",
+			formatted: `<b>bold</b> <span data-mx-spoiler></span>x<br>y`,
+			wantText:  "bold x\ny",
+		},
+		{
+			name: "去掉回复里引用的消息",
+			formatted: `<mx-reply><blockquote><a href="https://matrix.to/#/!room/$event">In reply to</a> ` +
+				`<span data-mx-spoiler>old</span></blockquote></mx-reply>new <span data-mx-spoiler>s</span>`,
+			wantText:     "new s",
+			wantEntities: []models.MessageEntity{spoiler(4, 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities := HTMLToSpoilers(tt.formatted)
+			if text != tt.wantText || !reflect.DeepEqual(entities, tt.wantEntities) {
+				t.Errorf("HTMLToSpoilers(%q) = %q, %+v, want %q, %+v", tt.formatted, text, entities, tt.wantText, tt.wantEntities)
+			}
+		})
+	}
+}
+
+func TestSpoilersRoundTrip(t *testing.T) {
+	// Telegram 的 spoiler 转换成 HTML 再转换回来应该不变
+	text := "喵 😺 meow 🐾 end"
+	entities := []models.MessageEntity{spoiler(2, 2), spoiler(5, 7)}
+	formatted, ok := EntitiesToHTML(text, entities, nil)
+	if !ok {
+		t.Fatalf("EntitiesToHTML(%q) returned ok = false", text)
+	}
+	gotText, gotEntities := HTMLToSpoilers(formatted)
+	if gotText != text || !reflect.DeepEqual(gotEntities, entities) {
+		t.Errorf("round trip of %q = %q, %+v, want %q, %+v", text, gotText, gotEntities, text, entities)
+	}
+}
@@ -0,0 +1,42 @@
+package misc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// geo: URI，RFC 5870
+
+func GeoURI(latitude, longitude, accuracy float64) string {
+	uri := "geo:" + formatCoordinate(latitude) + "," + formatCoordinate(longitude)
+	if accuracy > 0 {
+		uri += ";u=" + formatCoordinate(accuracy)
+	}
+	return uri
+}
+
+func formatCoordinate(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// 解析 geo: URI，只读取经纬度，忽略海拔和其他参数
+
+func ParseGeoURI(uri string) (latitude, longitude float64, err error) {
+	coords, found := strings.CutPrefix(uri, "geo:")
+	if !found {
+		err = fmt.Errorf("not a geo URI: %s", uri)
+		return
+	}
+	coords, _, _ = strings.Cut(coords, ";")
+	parts := strings.Split(coords, ",")
+	if len(parts) < 2 {
+		err = fmt.Errorf("invalid geo URI: %s", uri)
+		return
+	}
+	if latitude, err = strconv.ParseFloat(parts[0], 64); err != nil {
+		return
+	}
+	longitude, err = strconv.ParseFloat(parts[1], 64)
+	return
+}
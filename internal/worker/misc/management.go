@@ -0,0 +1,62 @@
+package misc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AsenHu/mewlink/internal/config"
+	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/id"
+)
+
+// 准备管理房间
+// 配置文件里指定了房间的话就加入这个房间，否则创建一个新的房间并保存到配置文件
+// 管理房间不能和任何联系人关联
+
+func EnsureManagementRoom(ctx context.Context, w *worker.Worker) (err error) {
+	roomID := w.Config.Get().Matrix.ManagementRoom
+	if roomID != "" {
+		index, err := w.DataBase.RoomList.GetIndexByRoomID(roomID)
+		if err != nil {
+			return err
+		}
+		if index != nil {
+			return fmt.Errorf("management room %s is linked to a contact", roomID)
+		}
+		_, err = w.Matrix.JoinRoomByID(ctx, roomID)
+		return err
+	}
+
+	resp, err := w.Matrix.CreateRoom(ctx, &mautrix.ReqCreateRoom{
+		Name:  "MewLink",
+		Topic: "MewLink management room, send !help for commands",
+		Invite: []id.UserID{
+			id.UserID(w.Config.Get().ServedUser),
+		},
+		IsDirect: true,
+		Preset:   "private_chat",
+	})
+	if err != nil {
+		return
+	}
+	log.Info().Str("RoomID", resp.RoomID.String()).Msg("Management room created")
+
+	return w.Config.Update(func(content *config.Content) error {
+		content.Matrix.ManagementRoom = resp.RoomID
+		return nil
+	})
+}
+
+// 向管理房间发送提醒，没有管理房间的时候什么都不做
+
+func Alert(ctx context.Context, w *worker.Worker, text string) {
+	roomID := w.Config.Get().Matrix.ManagementRoom
+	if roomID == "" || w.Matrix == nil || w.Matrix.AccessToken == "" {
+		return
+	}
+	if _, err := w.Matrix.SendNotice(ctx, roomID, text); err != nil {
+		log.Warn().Err(err).Msg("Failed to send alert to management room")
+	}
+}
@@ -0,0 +1,87 @@
+package misc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/id"
+)
+
+// 保存 Telegram 消息和 Matrix 事件的对应关系，失败的时候只记录日志
+
+func SaveMessage(w *worker.Worker, chatID int64, messageIDs []int, roomID id.RoomID, eventID id.EventID) {
+	SaveThreadMessage(w, chatID, messageIDs, roomID, eventID, "")
+}
+
+// 保存讨论串里的消息，threadID 是讨论串的根事件
+
+func SaveThreadMessage(w *worker.Worker, chatID int64, messageIDs []int, roomID id.RoomID, eventID, threadID id.EventID) {
+	if len(messageIDs) == 0 || eventID == "" {
+		return
+	}
+	info := &types.MessageInfo{
+		ChatID:    chatID,
+		RoomID:    roomID.String(),
+		EventID:   eventID.String(),
+		CreatedAt: time.Now().Unix(),
+		ThreadID:  threadID.String(),
+	}
+	for _, messageID := range messageIDs {
+		info.MessageIDs = append(info.MessageIDs, int64(messageID))
+	}
+	if err := w.DataBase.MessageList.Put(info); err != nil {
+		log.Err(err).
+			Int64("ChatID", chatID).
+			Str("EventID", eventID.String()).
+			Msg("Failed to save message mapping")
+	}
+	pruneMessages(w)
+}
+
+// 清理过期的对应关系的间隔，启动之后第一次保存的时候也会清理
+const pruneInterval = time.Hour
+
+type messagePruner struct {
+	mutex sync.Mutex
+	last  time.Time
+}
+
+var pruner messagePruner
+
+// 删除超过 MessageRetention 天的消息和投票的对应关系
+// 太早的消息回复的时候只是不能找到原来的消息，太早的投票不会再同步投票结果
+
+func pruneMessages(w *worker.Worker) {
+	days := w.Config.Get().MessageRetention
+	if days == 0 {
+		return
+	}
+
+	pruner.mutex.Lock()
+	if time.Since(pruner.last) < pruneInterval {
+		pruner.mutex.Unlock()
+		return
+	}
+	pruner.last = time.Now()
+	pruner.mutex.Unlock()
+
+	before := time.Now().Add(-time.Duration(days) * 24 * time.Hour).Unix()
+	removed, err := w.DataBase.MessageList.Prune(before)
+	if err != nil {
+		log.Err(err).Msg("Failed to prune message mappings")
+	} else if removed != 0 {
+		log.Info().Int("Removed", removed).Msg("Old message mappings pruned")
+	}
+
+	removed, err = w.DataBase.PollList.Prune(before)
+	if err != nil {
+		log.Err(err).Msg("Failed to prune polls")
+		return
+	}
+	if removed != 0 {
+		log.Info().Int("Removed", removed).Msg("Old polls pruned")
+	}
+}
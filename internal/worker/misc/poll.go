@@ -0,0 +1,34 @@
+package misc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+	"maunium.net/go/mautrix/event"
+)
+
+// mautrix 没有定义投票结束的事件类型
+var EventUnstablePollEnd = event.Type{Type: "org.matrix.msc3381.poll.end", Class: event.MessageEventType}
+
+// 投票的文本形式，给不支持投票的客户端看
+
+func PollText(question string, options []string) string {
+	var b strings.Builder
+	b.WriteString(question)
+	for i, option := range options {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, option)
+	}
+	return b.String()
+}
+
+// 投票结果的文本形式
+
+func PollResultText(poll *models.Poll) string {
+	var b strings.Builder
+	b.WriteString("The poll has ended: " + poll.Question)
+	for i, option := range poll.Options {
+		fmt.Fprintf(&b, "\n%d. %s - %d vote(s)", i+1, option.Text, option.VoterCount)
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package misc
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+联系人限流的令牌桶
+
+两边的 Worker 都要用到：Telegram 那边在收到消息的时候消耗令牌，Matrix 那边解除禁言的时候重置
+*/
+
+const floodWindow = 10 * time.Minute
+
+// 联系人数量超过这个值的时候，清理掉已经恢复正常的联系人
+const maxLimitedContacts = 4096
+
+type LimitResult uint8
+
+const (
+	LimitPass  LimitResult = iota // 正常处理
+	LimitFlood                    // 刚刚超限，需要警告联系人
+	LimitDrop                     // 继续超限，直接丢弃
+	LimitMute                     // 超限次数太多，需要禁言
+	LimitMuted                    // 正在禁言，直接丢弃
+)
+
+type contactLimit struct {
+	tokens     float64
+	updated    time.Time
+	flooding   bool
+	dropped    int
+	floods     []time.Time
+	mutedUntil time.Time
+}
+
+type rateLimiter struct {
+	mutex    sync.Mutex
+	contacts map[int64]*contactLimit
+}
+
+var limiter = rateLimiter{contacts: map[int64]*contactLimit{}}
+
+// 消耗一个令牌
+// 结果是 LimitPass 的时候，dropped 是上一次超限丢弃的消息数量
+
+func (l *rateLimiter) take(chatID int64, rate, burst, muteAfter int64, muteDuration time.Duration) (result LimitResult, dropped int) {
+	now := time.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	c, exi := l.contacts[chatID]
+	if !exi {
+		if len(l.contacts) >= maxLimitedContacts {
+			l.prune(now, rate, burst)
+		}
+		c = &contactLimit{tokens: float64(burst), updated: now}
+		l.contacts[chatID] = c
+	}
+	if now.Before(c.mutedUntil) {
+		return LimitMuted, 0
+	}
+
+	// 补充令牌
+	c.tokens += now.Sub(c.updated).Minutes() * float64(rate)
+	c.tokens = min(c.tokens, float64(burst))
+	c.updated = now
+
+	if c.tokens >= 1 {
+		c.tokens--
+		if c.flooding {
+			c.flooding = false
+			dropped = c.dropped
+			c.dropped = 0
+		}
+		return LimitPass, dropped
+	}
+
+	c.dropped++
+	if c.flooding {
+		return LimitDrop, 0
+	}
+	c.flooding = true
+
+	// 只保留 floodWindow 内的超限记录
+	floods := c.floods[:0]
+	for _, t := range c.floods {
+		if now.Sub(t) < floodWindow {
+			floods = append(floods, t)
+		}
+	}
+	c.floods = append(floods, now)
+
+	if muteAfter != 0 && muteDuration != 0 && int64(len(c.floods)) >= muteAfter {
+		c.floods = nil
+		c.flooding = false
+		c.dropped = 0
+		c.mutedUntil = now.Add(muteDuration)
+		return LimitMute, 0
+	}
+	return LimitFlood, 0
+}
+
+// 令牌已经补满，也没有超限记录的联系人和新的联系人没有区别，可以删除
+
+func (l *rateLimiter) prune(now time.Time, rate, burst int64) {
+	for chatID, c := range l.contacts {
+		full := c.tokens+now.Sub(c.updated).Minutes()*float64(rate) >= float64(burst)
+		if full && !c.flooding && len(c.floods) == 0 && now.After(c.mutedUntil) {
+			delete(l.contacts, chatID)
+		}
+	}
+}
+
+// 解除联系人的禁言，并重置令牌桶
+
+func ResetRateLimit(chatID int64) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	delete(limiter.contacts, chatID)
+}
+
+// 消耗联系人的一个令牌，rate 是每分钟补充的令牌数量，muteAfter 或者 muteDuration 为 0 的时候不禁言
+
+func TakeRateLimit(chatID int64, rate, burst, muteAfter int64, muteDuration time.Duration) (result LimitResult, dropped int) {
+	return limiter.take(chatID, rate, burst, muteAfter, muteDuration)
+}
@@ -0,0 +1,74 @@
+package misc
+
+import (
+	"context"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// 为联系人创建一个新的房间，并邀请被服务的用户
+// avatar 为空的时候不设置头像
+
+func CreateRoom(ctx context.Context, w *worker.Worker, name string, avatar id.ContentURIString) (roomID id.RoomID, err error) {
+	var initialState []*event.Event
+	if avatar != "" {
+		initialState = append(initialState, &event.Event{
+			Type:    event.StateRoomAvatar,
+			Content: event.Content{Parsed: &event.RoomAvatarEventContent{URL: avatar}},
+		})
+	}
+
+	resp, err := w.Matrix.CreateRoom(ctx, &mautrix.ReqCreateRoom{
+		Name:         name,
+		InitialState: initialState,
+		Invite: []id.UserID{
+			id.UserID(w.Config.Get().ServedUser),
+		},
+		IsDirect: true,
+		Preset:   "private_chat",
+	})
+	if err != nil {
+		return
+	}
+	roomID = resp.RoomID
+	return
+}
+
+// 为处于 Dormant 或 Archived 状态的联系人重新创建房间
+// 旧房间里已经没有被服务的用户了，迁移之后 Bot 也会离开旧房间
+
+func ReopenRoom(ctx context.Context, w *worker.Worker, index []byte) (info *types.RoomInfo, err error) {
+	var oldRoomID id.RoomID
+	info, err = w.DataBase.RoomList.MoveRoomByIndex(index, func(info *types.RoomInfo) (id.RoomID, error) {
+		// 其他 goroutine 可能已经重新创建过了
+		if info.GetLink() == types.RoomInfo_Linked {
+			return "", nil
+		}
+		roomID, err := CreateRoom(ctx, w, info.GetRoomName(), id.ContentURIString(info.GetAvatar()))
+		if err != nil {
+			return "", err
+		}
+		oldRoomID = id.RoomID(info.GetRoomID())
+		info.Link = types.RoomInfo_Linked
+		return roomID, nil
+	})
+	if err != nil || oldRoomID == "" {
+		return
+	}
+
+	log.Info().
+		Int64("ChatID", info.GetChatID()).
+		Str("OldRoomID", oldRoomID.String()).
+		Str("RoomID", info.GetRoomID()).
+		Msg("Room reopened")
+
+	if _, err := w.Matrix.LeaveRoom(ctx, oldRoomID); err != nil {
+		log.Warn().Err(err).Msg("Failed to leave old room")
+	}
+	return
+}
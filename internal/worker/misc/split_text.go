@@ -0,0 +1,150 @@
+package misc
+
+import (
+	"unicode/utf16"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// Telegram 的长度限制，单位是 UTF-16 code unit
+
+const (
+	MaxTextLength    = 4096
+	MaxCaptionLength = 1024
+)
+
+type TextChunk struct {
+	Text     string
+	Entities []models.MessageEntity
+}
+
+// 分段的优先级，越靠前越优先，先找段落，再找句子，最后找空格
+var splitSeparators = [][]uint16{
+	utf16.Encode([]rune("\n\n")),
+	utf16.Encode([]rune("\n")),
+	utf16.Encode([]rune(". ")),
+	utf16.Encode([]rune("! ")),
+	utf16.Encode([]rune("? ")),
+	utf16.Encode([]rune("。")),
+	utf16.Encode([]rune("！")),
+	utf16.Encode([]rune("？")),
+	utf16.Encode([]rune(" ")),
+}
+
+// Telegram 的文本长度，单位是 UTF-16 code unit
+
+func UTF16Len(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+// 把文本拆分成不超过 limit 的几段
+// 跨越分段位置的格式会拆到每一段里，每一段的格式都是完整的
+
+func SplitText(text string, entities []models.MessageEntity, limit int) (chunks []TextChunk) {
+	units := utf16.Encode([]rune(text))
+	if len(units) <= limit {
+		return []TextChunk{{Text: text, Entities: entities}}
+	}
+
+	start := 0
+	for start < len(units) {
+		end := min(start+limit, len(units))
+		next := end
+		if end < len(units) {
+			end = findSplit(units, start, end)
+			next = end
+		}
+		// 分段位置两边的空白没有意义
+		for end > start && isSpace(units[end-1]) {
+			end--
+		}
+		for next < len(units) && isSpace(units[next]) {
+			next++
+		}
+		if end > start {
+			chunks = append(chunks, TextChunk{
+				Text:     string(utf16.Decode(units[start:end])),
+				Entities: clipEntities(entities, start, end),
+			})
+		}
+		start = next
+	}
+	return
+}
+
+// 在 (start, end] 里找一个分段的位置，分段位置在分隔符之后
+// 只在后半段里找，避免分出太短的段
+
+func findSplit(units []uint16, start, end int) int {
+	lowest := start + (end-start)/2
+	for _, sep := range splitSeparators {
+		for i := end - len(sep); i >= lowest; i-- {
+			if equalUnits(units[i:i+len(sep)], sep) {
+				return i + len(sep)
+			}
+		}
+	}
+	// 找不到的时候直接截断，但是不能截断代理对
+	if utf16.IsSurrogate(rune(units[end-1])) && units[end-1] < 0xDC00 {
+		end--
+	}
+	return end
+}
+
+func equalUnits(a, b []uint16) bool {
+	for i := range b {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isSpace(u uint16) bool {
+	return u == ' ' || u == '\n' || u == '\t'
+}
+
+// 截取 [start, end) 里的格式，并转换成相对于 start 的位置
+
+func clipEntities(entities []models.MessageEntity, start, end int) (clipped []models.MessageEntity) {
+	for _, entity := range entities {
+		from := max(entity.Offset, start)
+		to := min(entity.Offset+entity.Length, end)
+		if from >= to {
+			continue
+		}
+		entity.Offset = from - start
+		entity.Length = to - from
+		clipped = append(clipped, entity)
+	}
+	return
+}
+
+// 把说明文字拆分成不超过 MaxCaptionLength 的说明文字和剩下的文字
+// 剩下的文字需要作为普通消息发送，不需要拆分的时候 rest 为空
+
+func SplitCaption(text string, entities []models.MessageEntity) (caption, rest TextChunk) {
+	units := utf16.Encode([]rune(text))
+	if len(units) <= MaxCaptionLength {
+		caption = TextChunk{Text: text, Entities: entities}
+		return
+	}
+
+	end := findSplit(units, 0, MaxCaptionLength)
+	next := end
+	for end > 0 && isSpace(units[end-1]) {
+		end--
+	}
+	for next < len(units) && isSpace(units[next]) {
+		next++
+	}
+	caption = TextChunk{
+		Text:     string(utf16.Decode(units[:end])),
+		Entities: clipEntities(entities, 0, end),
+	}
+	rest = TextChunk{
+		Text:     string(utf16.Decode(units[next:])),
+		Entities: clipEntities(entities, next, len(units)),
+	}
+	return
+}
@@ -0,0 +1,176 @@
+package misc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func bold(offset, length int) models.MessageEntity {
+	return models.MessageEntity{Type: models.MessageEntityTypeBold, Offset: offset, Length: length}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []models.MessageEntity
+		limit    int
+		want     []TextChunk
+	}{
+		{
+			name:     "不需要拆分",
+			text:     "hello",
+			entities: []models.MessageEntity{bold(0, 5)},
+			limit:    5,
+			want:     []TextChunk{{Text: "hello", Entities: []models.MessageEntity{bold(0, 5)}}},
+		},
+		{
+			name:  "优先在段落之间拆分",
+			text:  "aaaa\n\nbb cc",
+			limit: 9,
+			want:  []TextChunk{{Text: "aaaa"}, {Text: "bb cc"}},
+		},
+		{
+			name:  "没有分隔符的时候不截断代理对",
+			text:  "😀😀😀",
+			limit: 3,
+			want:  []TextChunk{{Text: "😀"}, {Text: "😀"}, {Text: "😀"}},
+		},
+		{
+			name:     "跨越分段位置的格式拆到两段里",
+			text:     "aaaa bbbb",
+			entities: []models.MessageEntity{bold(2, 5)},
+			limit:    5,
+			want: []TextChunk{
+				{Text: "aaaa", Entities: []models.MessageEntity{bold(2, 2)}},
+				{Text: "bbbb", Entities: []models.MessageEntity{bold(0, 2)}},
+			},
+		},
+		{
+			name:     "格式的位置按照 UTF-16 计算",
+			text:     "😀😀 ab",
+			entities: []models.MessageEntity{bold(2, 5)},
+			limit:    5,
+			want: []TextChunk{
+				{Text: "😀😀", Entities: []models.MessageEntity{bold(2, 2)}},
+				{Text: "ab", Entities: []models.MessageEntity{bold(0, 2)}},
+			},
+		},
+		{
+			name:     "只在被去掉的空白上的格式会被丢弃",
+			text:     "aaaa    bbbb",
+			entities: []models.MessageEntity{bold(5, 2)},
+			limit:    6,
+			want:     []TextChunk{{Text: "aaaa"}, {Text: "bbbb"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.entities, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitText(%q, %d) = %+v, want %+v", tt.text, tt.limit, got, tt.want)
+			}
+			for _, chunk := range got {
+				if n := UTF16Len(chunk.Text); n > tt.limit {
+					t.Errorf("chunk %q is %d units long, limit %d", chunk.Text, n, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitTextLong(t *testing.T) {
+	// 拆分之后的文字拼起来应该和原来的一样，只是少了分段位置的空白
+	text := strings.Repeat("Meow meow. 喵喵喵！😺 ", 500)
+	chunks := SplitText(text, nil, MaxTextLength)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want at least 2", len(chunks))
+	}
+	var joined []string
+	for _, chunk := range chunks {
+		if n := UTF16Len(chunk.Text); n > MaxTextLength {
+			t.Errorf("chunk is %d units long, limit %d", n, MaxTextLength)
+		}
+		joined = append(joined, chunk.Text)
+	}
+	if got, want := strings.Join(strings.Fields(strings.Join(joined, " ")), " "), strings.Join(strings.Fields(text), " "); got != want {
+		t.Errorf("joined chunks do not match the original text")
+	}
+}
+
+func TestClipEntities(t *testing.T) {
+	tests := []struct {
+		name       string
+		entities   []models.MessageEntity
+		start, end int
+		want       []models.MessageEntity
+	}{
+		{
+			name:     "完全在范围里",
+			entities: []models.MessageEntity{bold(3, 2)},
+			start:    2, end: 6,
+			want: []models.MessageEntity{bold(1, 2)},
+		},
+		{
+			name:     "两边都超出范围",
+			entities: []models.MessageEntity{bold(0, 10)},
+			start:    2, end: 6,
+			want: []models.MessageEntity{bold(0, 4)},
+		},
+		{
+			name:     "在范围外面",
+			entities: []models.MessageEntity{bold(0, 2), bold(6, 2)},
+			start:    2, end: 6,
+		},
+		{
+			name: "嵌套的格式分别截取",
+			entities: []models.MessageEntity{
+				bold(0, 8),
+				{Type: MessageEntityTypeSpoiler, Offset: 4, Length: 4},
+			},
+			start: 2, end: 6,
+			want: []models.MessageEntity{
+				bold(0, 4),
+				{Type: MessageEntityTypeSpoiler, Offset: 2, Length: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clipEntities(tt.entities, tt.start, tt.end)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clipEntities(%d, %d) = %+v, want %+v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCaption(t *testing.T) {
+	// 不需要拆分的时候 rest 为空
+	caption, rest := SplitCaption("short", []models.MessageEntity{bold(0, 5)})
+	if caption.Text != "short" || len(caption.Entities) != 1 || rest.Text != "" {
+		t.Errorf("SplitCaption(%q) = %+v, %+v", "short", caption, rest)
+	}
+
+	// 说明文字在空格处拆分，跨越分段位置的格式拆到两边
+	text := strings.Repeat("😺", 400) + " " + strings.Repeat("a", 300)
+	caption, rest = SplitCaption(text, []models.MessageEntity{bold(790, 20)})
+	if n := UTF16Len(caption.Text); n > MaxCaptionLength {
+		t.Errorf("caption is %d units long, limit %d", n, MaxCaptionLength)
+	}
+	if want := strings.Repeat("😺", 400); caption.Text != want {
+		t.Errorf("caption = %q, want %q", caption.Text, want)
+	}
+	if want := strings.Repeat("a", 300); rest.Text != want {
+		t.Errorf("rest = %q, want %q", rest.Text, want)
+	}
+	if want := []models.MessageEntity{bold(790, 10)}; !reflect.DeepEqual(caption.Entities, want) {
+		t.Errorf("caption entities = %+v, want %+v", caption.Entities, want)
+	}
+	if want := []models.MessageEntity{bold(0, 9)}; !reflect.DeepEqual(rest.Entities, want) {
+		t.Errorf("rest entities = %+v, want %+v", rest.Entities, want)
+	}
+}
@@ -0,0 +1,35 @@
+package misc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/go-telegram/bot"
+)
+
+// 从 Telegram 下载文件
+
+func DownloadTelegramFile(ctx context.Context, w *worker.Worker, fileID string) (data []byte, err error) {
+	file, err := w.Telegram.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.Telegram.FileDownloadLink(file), nil)
+	if err != nil {
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to download file %s: %s", fileID, resp.Status)
+		return
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -2,31 +2,148 @@ package misc
 
 import (
 	"context"
+	"net/http"
+	"time"
 
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker"
+	"github.com/go-telegram/bot"
+	"github.com/rs/zerolog/log"
 	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
 )
 
-/*
-func (w *Worker) UpdateRoomNameByInput(info *database.RoomInfo, name string) (err error) {
-	if info.RoomName == name {
-		return nil
+// 把联系人在 Telegram 上的名字和头像同步到 Matrix 房间
+// 每个房间在 ProfileSyncInterval 内最多同步一次，被固定的房间名和头像不会被修改
+
+func UpdateProfile(ctx context.Context, w *worker.Worker, index []byte) (err error) {
+	if index == nil {
+		return
 	}
 
-	// 准备结构体
-	content := event.RoomNameEventContent{
-		Name: name,
+	// 先占住这次同步，避免同时收到多条消息的时候重复同步
+	interval := time.Duration(w.Config.Get().Telegram.ProfileSyncInterval) * time.Second
+	now := time.Now()
+	claimed := false
+	info, err := w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		if now.Sub(time.Unix(info.GetProfileUpdatedAt(), 0)) < interval {
+			return false
+		}
+		info.ProfileUpdatedAt = now.Unix()
+		claimed = true
+		return true
+	})
+	if err != nil || !claimed {
+		return
+	}
+	// 房间不可用的时候没必要同步
+	if info.GetLink() != types.RoomInfo_Linked || info.GetStatus() != types.RoomInfo_Active {
+		return
+	}
+
+	if !info.GetPinRoomName() {
+		if err = updateRoomName(ctx, w, index, info); err != nil {
+			return
+		}
+	}
+	if !info.GetPinAvatar() {
+		err = updateAvatar(ctx, w, index, info)
+	}
+	return
+}
+
+func updateRoomName(ctx context.Context, w *worker.Worker, index []byte, info *types.RoomInfo) (err error) {
+	chat, err := w.Telegram.GetChat(ctx, &bot.GetChatParams{ChatID: info.GetChatID()})
+	if err != nil {
+		return
+	}
+	// 顺便更新 Username
+	if chat.Username != info.GetUsername() {
+		_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+			info.Username = chat.Username
+			return true
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	name := UserName(chat.FirstName, chat.LastName, chat.Username, chat.ID)
+	if name == info.GetRoomName() {
+		return
 	}
-	// 更新房间名
-	_, err = w.Matrix.SendMessageEvent(context.Background(), info.RoomID, event.StateRoomName, content)
+
+	_, err = w.Matrix.SendStateEvent(ctx, id.RoomID(info.GetRoomID()), event.StateRoomName, "", &event.RoomNameEventContent{
+		Name: name,
+	})
 	if err != nil {
 		return
 	}
-	info.RoomName = name
+	log.Info().
+		Int64("ChatID", info.GetChatID()).
+		Str("OldName", info.GetRoomName()).
+		Str("Name", name).
+		Msg("Room name updated")
+
+	_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		info.RoomName = name
+		return true
+	})
 	return
 }
-*/
 
-func UpdateProfile(ctx context.Context, index []byte, cli *mautrix.Client) (err error) {
-	// 等待实现
+func updateAvatar(ctx context.Context, w *worker.Worker, index []byte, info *types.RoomInfo) (err error) {
+	photos, err := w.Telegram.GetUserProfilePhotos(ctx, &bot.GetUserProfilePhotosParams{
+		UserID: info.GetChatID(),
+		Limit:  1,
+	})
+	if err != nil {
+		return
+	}
+
+	// 联系人删除了头像，或者隐藏了头像
+	var uniqueID string
+	var avatar id.ContentURIString
+	if len(photos.Photos) != 0 && len(photos.Photos[0]) != 0 {
+		// 最后一个是最大的尺寸
+		photo := photos.Photos[0][len(photos.Photos[0])-1]
+		uniqueID = photo.FileUniqueID
+		if uniqueID == info.GetAvatarUniqueID() {
+			return
+		}
+
+		// 头像只在 file_unique_id 变化的时候上传一次
+		var data []byte
+		data, err = DownloadTelegramFile(ctx, w, photo.FileID)
+		if err != nil {
+			return
+		}
+		var resp *mautrix.RespMediaUpload
+		resp, err = w.Matrix.UploadBytes(ctx, data, http.DetectContentType(data))
+		if err != nil {
+			return
+		}
+		avatar = resp.ContentURI.CUString()
+	} else if info.GetAvatarUniqueID() == "" {
+		return
+	}
+
+	_, err = w.Matrix.SendStateEvent(ctx, id.RoomID(info.GetRoomID()), event.StateRoomAvatar, "", &event.RoomAvatarEventContent{
+		URL: avatar,
+	})
+	if err != nil {
+		return
+	}
+	log.Info().
+		Int64("ChatID", info.GetChatID()).
+		Str("Avatar", string(avatar)).
+		Msg("Room avatar updated")
+
+	_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		info.Avatar = string(avatar)
+		info.AvatarUniqueID = uniqueID
+		return true
+	})
 	return
 }
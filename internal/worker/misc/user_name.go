@@ -0,0 +1,26 @@
+package misc
+
+import "strconv"
+
+// 整理 Telegram 用户的显示名称
+
+func UserName(firstName, lastName, username string, chatID int64) string {
+	// 1. 尝试拼接 FirstName 和 LastName
+	if firstName != "" || lastName != "" {
+		if firstName == "" {
+			return lastName
+		}
+		if lastName == "" {
+			return firstName
+		}
+		return firstName + " " + lastName
+	}
+
+	// 2. 如果拼接失败，使用 Username
+	if username != "" {
+		return username
+	}
+
+	// 3. 如果 Username 为空，使用 UserID
+	return strconv.FormatInt(chatID, 10)
+}
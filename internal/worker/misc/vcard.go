@@ -0,0 +1,119 @@
+package misc
+
+import (
+	"strings"
+)
+
+/*
+vCard
+
+Telegram 的联系人只有名字和电话号码，以及一个可选的 vCard
+这里只处理这几个字段，其他字段会原样保留在 vCard 里
+*/
+
+type VCardContact struct {
+	FirstName   string
+	LastName    string
+	PhoneNumber string
+}
+
+var vCardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`)
+var vCardUnescaper = strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n")
+
+// 生成 vCard 3.0
+
+func GenerateVCard(contact VCardContact) string {
+	first := vCardEscaper.Replace(contact.FirstName)
+	last := vCardEscaper.Replace(contact.LastName)
+	full := strings.TrimSpace(first + " " + last)
+
+	lines := []string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"N:" + last + ";" + first + ";;;",
+		"FN:" + full,
+	}
+	if contact.PhoneNumber != "" {
+		lines = append(lines, "TEL;TYPE=CELL:"+vCardEscaper.Replace(contact.PhoneNumber))
+	}
+	lines = append(lines, "END:VCARD")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// 解析 vCard，只读取第一个联系人的名字和第一个电话号码
+
+func ParseVCard(data string) (contact VCardContact) {
+	// 折叠的行以空格或者制表符开头
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var fullName string
+lines:
+	for _, line := range strings.Split(data, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		// 去掉参数和分组，例如 item1.TEL;TYPE=CELL
+		name, _, _ := strings.Cut(key, ";")
+		if i := strings.LastIndex(name, "."); i != -1 {
+			name = name[i+1:]
+		}
+
+		switch strings.ToUpper(name) {
+		case "N":
+			if contact.FirstName != "" || contact.LastName != "" {
+				continue
+			}
+			parts := splitVCardValue(value)
+			if len(parts) > 0 {
+				contact.LastName = parts[0]
+			}
+			if len(parts) > 1 {
+				contact.FirstName = parts[1]
+			}
+		case "FN":
+			if fullName == "" {
+				fullName = vCardUnescaper.Replace(value)
+			}
+		case "TEL":
+			if contact.PhoneNumber == "" {
+				contact.PhoneNumber = strings.TrimPrefix(vCardUnescaper.Replace(value), "tel:")
+			}
+		case "END":
+			if strings.EqualFold(value, "VCARD") {
+				break lines
+			}
+		}
+	}
+
+	// 没有 N 的时候使用 FN
+	if contact.FirstName == "" && contact.LastName == "" {
+		contact.FirstName = fullName
+	}
+	return
+}
+
+// 按照没有转义的分号拆分
+
+func splitVCardValue(value string) (parts []string) {
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ';':
+			parts = append(parts, vCardUnescaper.Replace(b.String()))
+			b.Reset()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return append(parts, vCardUnescaper.Replace(b.String()))
+}
@@ -0,0 +1,124 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AsenHu/mewlink/internal/database"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+// 已经收到过自动回复的用户，每次运行只回复一次，避免刷屏
+var blockReplied sync.Map
+
+// 检查用户是否在配置文件或者数据库的列表里
+
+func (w *TelegramWorker) isListed(kind database.AccessKind, chat models.Chat) bool {
+	if w.DataBase.AccessList.Contains(kind, chat.ID, chat.Username) {
+		return true
+	}
+	list := w.Config.Get().Telegram.AllowList
+	if kind == database.AccessDeny {
+		list = w.Config.Get().Telegram.DenyList
+	}
+	id, username := strconv.FormatInt(chat.ID, 10), "@"+strings.ToLower(chat.Username)
+	for _, entry := range list {
+		entry, err := database.NormalizeAccessEntry(entry)
+		if err != nil {
+			continue
+		}
+		if entry == id || (chat.Username != "" && entry == username) {
+			return true
+		}
+	}
+	return false
+}
+
+// 不在屏蔽列表里，开启了只允许列表时在允许列表里，列表都在内存里
+
+func (w *TelegramWorker) isAllowed(chat models.Chat) bool {
+	allowed := !w.isListed(database.AccessDeny, chat)
+	if allowed && w.Config.Get().Telegram.AllowListOnly {
+		allowed = w.isListed(database.AccessAllow, chat)
+	}
+	return allowed
+}
+
+// update 来自的联系人，所有会转发到 Matrix 的 update 都要检查
+// 投票结束的 update 里没有联系人，要通过投票找到联系人，找不到的时候 ok 为 false
+
+func (w *TelegramWorker) updateChat(update *models.Update) (chat models.Chat, ok bool) {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat, true
+	case update.EditedMessage != nil:
+		return update.EditedMessage.Chat, true
+	case update.MyChatMember != nil:
+		return update.MyChatMember.Chat, true
+	case update.PollAnswer != nil && update.PollAnswer.User != nil:
+		user := update.PollAnswer.User
+		return models.Chat{
+			ID:        user.ID,
+			Type:      models.ChatTypePrivate,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Username:  user.Username,
+		}, true
+	case update.Poll != nil:
+		pollInfo, err := w.DataBase.PollList.GetByPollID(update.Poll.ID)
+		if err != nil {
+			log.Err(err).Msg("Failed to get poll")
+			return
+		}
+		if pollInfo == nil {
+			return
+		}
+		chat = models.Chat{ID: pollInfo.GetChatID(), Type: models.ChatTypePrivate}
+		// 用 username 屏蔽的联系人也要能找到
+		_, info, err := w.DataBase.RoomList.GetRoomInfoByChatID(chat.ID)
+		if err != nil {
+			log.Err(err).Msg("Failed to get RoomInfo by ChatID")
+		} else if info != nil {
+			chat.Username = info.GetUsername()
+		}
+		return chat, true
+	}
+	return
+}
+
+// 检查用户是否可以使用 Bot，这一步在处理 update 之前，除了查找投票不会读写数据库
+// 返回 false 代表消息被丢弃
+
+func (w *TelegramWorker) checkAccess(ctx context.Context, update *models.Update) bool {
+	chat, ok := w.updateChat(update)
+	if !ok || w.isAllowed(chat) {
+		return true
+	}
+
+	log.Info().
+		Int64("ChatID", chat.ID).
+		Str("User", misc.UserName(chat.FirstName, chat.LastName, chat.Username, chat.ID)).
+		Msg("Update from blocked user dropped")
+
+	// 只回复联系人发来的消息，投票和 Bot 状态变化之类的 update 直接丢弃
+	reply := w.Config.Get().Telegram.BlockReply
+	if reply == "" || update.Message == nil {
+		return false
+	}
+	if _, replied := blockReplied.LoadOrStore(chat.ID, struct{}{}); replied {
+		return false
+	}
+	_, err := w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chat.ID,
+		Text:   reply,
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Telegram")
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/database"
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+/*
+联系人审批
+
+审批模式下，没有房间的联系人发来的消息会先放进等待列表，并在管理房间里发送一条审批提示
+审批通过之后才会创建房间，并按顺序转发之前缓存的消息（见 misc.ApprovePending）
+审批被拒绝的联系人会收到 DenyReply，之后的消息都会被丢弃
+*/
+
+// 每个联系人最多缓存的消息数量
+const maxPendingUpdates = 100
+
+// 检查联系人是否可以继续处理，返回 false 代表消息已经被放进等待列表或者被丢弃
+
+func (w *TelegramWorker) checkApproval(ctx context.Context, update *models.Update) bool {
+	if !w.Config.Get().Telegram.ApprovalMode {
+		return true
+	}
+	chat := update.Message.Chat
+
+	// 审批通过的时候会拿着这个锁转发缓存的消息，新的消息要等缓存的消息转发完
+	unlock := misc.LockApproval(w.Worker, chat.ID)
+	defer unlock()
+
+	needs, err := w.needsApproval(chat)
+	if err != nil {
+		log.Err(err).Msg("Failed to get index by ChatID")
+		w.sendErrToTG(ctx, chat.ID, err)
+		return false
+	}
+	if !needs {
+		return true
+	}
+
+	raw, err := json.Marshal(update)
+	if err != nil {
+		log.Err(err).Msg("Failed to marshal update")
+		return false
+	}
+
+	// 放进等待列表
+	isNew, denied := false, false
+	pending, err := w.DataBase.PendingList.Update(chat.ID, func(info *types.PendingInfo) bool {
+		if info.GetDenied() {
+			denied = true
+			return false
+		}
+		if info.GetChatID() == 0 {
+			isNew = true
+			info.ChatID = chat.ID
+			info.CreatedAt = time.Now().Unix()
+		}
+		info.FirstName = chat.FirstName
+		info.LastName = chat.LastName
+		info.Username = chat.Username
+		// `/start` 在审批通过的时候会重新处理，不需要缓存
+		if update.Message.Text != "/start" && len(info.GetUpdates()) < maxPendingUpdates {
+			info.Updates = append(info.Updates, raw)
+		}
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to update pending list")
+		w.sendErrToTG(ctx, chat.ID, err)
+		return false
+	}
+	if denied {
+		log.Debug().Int64("ChatID", chat.ID).Msg("Message from denied contact dropped")
+		return false
+	}
+	if !isNew {
+		log.Debug().Int64("ChatID", chat.ID).Msg("Message from pending contact buffered")
+		return false
+	}
+
+	log.Info().
+		Int64("ChatID", chat.ID).
+		Str("User", getUserName(update)).
+		Msg("New contact waiting for approval")
+
+	// 在管理房间里发送审批提示
+	w.sendApprovalNotice(ctx, pending)
+
+	_, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chat.ID,
+		Text:   "Your request to chat has been sent, please wait for approval 🐾",
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Telegram")
+	}
+	return false
+}
+
+// 联系人是否需要审批，审批模式下不在允许列表里，也没有房间的联系人需要审批
+
+func (w *TelegramWorker) needsApproval(chat models.Chat) (needs bool, err error) {
+	if !w.Config.Get().Telegram.ApprovalMode || w.isListed(database.AccessAllow, chat) {
+		return
+	}
+	chatLock, _ := w.DataBase.RoomList.ChatIDMutex.LoadOrStore(chat.ID, &sync.RWMutex{})
+	chatLock.(*sync.RWMutex).RLock()
+	index, err := w.DataBase.RoomList.GetIndexByChatID(chat.ID)
+	chatLock.(*sync.RWMutex).RUnlock()
+	return index == nil, err
+}
+
+func (w *TelegramWorker) sendApprovalNotice(ctx context.Context, pending *types.PendingInfo) {
+	roomID := w.Config.Get().Matrix.ManagementRoom
+	if roomID == "" {
+		log.Warn().Int64("ChatID", pending.GetChatID()).Msg("No management room, can not ask for approval")
+		return
+	}
+
+	name := misc.UserName(pending.GetFirstName(), pending.GetLastName(), pending.GetUsername(), pending.GetChatID())
+	if pending.GetUsername() != "" {
+		name += " (@" + pending.GetUsername() + ")"
+	}
+	text := fmt.Sprintf("New contact %s wants to chat, ChatID %d\nReact with 👍 to approve or 👎 to deny, or send !approve %d / !deny %d",
+		name, pending.GetChatID(), pending.GetChatID(), pending.GetChatID())
+	resp, err := w.Matrix.SendNotice(ctx, roomID, text)
+	if err != nil {
+		log.Err(err).Msg("Failed to send approval notice to Matrix")
+		return
+	}
+
+	_, err = w.DataBase.PendingList.Update(pending.GetChatID(), func(info *types.PendingInfo) bool {
+		if info.GetChatID() == 0 {
+			return false
+		}
+		info.NoticeEventID = resp.EventID.String()
+		return true
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to update pending list")
+	}
+}
@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/id"
+)
+
+// 自定义表情的图片只上传一次，数量超过这个值的时候清空重新上传
+const maxCustomEmojis = 1024
+
+type customEmojiCache struct {
+	mutex sync.Mutex
+	uris  map[string]id.ContentURIString
+}
+
+var customEmojis = customEmojiCache{uris: map[string]id.ContentURIString{}}
+
+// 获取自定义表情在 Matrix 上的图片
+// 动态的表情只能使用缩略图，没有缩略图或者获取失败的表情不会出现在结果里
+
+func (w *TelegramWorker) customEmojiURIs(ctx context.Context, entities []models.MessageEntity) (uris map[string]id.ContentURIString) {
+	uris = map[string]id.ContentURIString{}
+	var missing []string
+	customEmojis.mutex.Lock()
+	for _, entity := range entities {
+		if entity.Type != models.MessageEntityTypeCustomEmoji {
+			continue
+		}
+		if uri, exi := customEmojis.uris[entity.CustomEmojiID]; exi {
+			uris[entity.CustomEmojiID] = uri
+		} else {
+			missing = append(missing, entity.CustomEmojiID)
+		}
+	}
+	customEmojis.mutex.Unlock()
+	if len(missing) == 0 {
+		return
+	}
+
+	stickers, err := w.Telegram.GetCustomEmojiStickers(ctx, &bot.GetCustomEmojiStickersParams{CustomEmojiIDs: missing})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get custom emoji")
+		return
+	}
+	for _, sticker := range stickers {
+		fileID := sticker.FileID
+		if sticker.IsAnimated || sticker.IsVideo {
+			if sticker.Thumbnail == nil {
+				continue
+			}
+			fileID = sticker.Thumbnail.FileID
+		}
+		data, err := misc.DownloadTelegramFile(ctx, w.Worker, fileID)
+		if err != nil {
+			log.Warn().Err(err).Str("CustomEmojiID", sticker.CustomEmojiID).Msg("Failed to download custom emoji")
+			continue
+		}
+		resp, err := w.Matrix.UploadBytes(ctx, data, http.DetectContentType(data))
+		if err != nil {
+			log.Warn().Err(err).Str("CustomEmojiID", sticker.CustomEmojiID).Msg("Failed to upload custom emoji")
+			continue
+		}
+		uris[sticker.CustomEmojiID] = resp.ContentURI.CUString()
+
+		customEmojis.mutex.Lock()
+		if len(customEmojis.uris) >= maxCustomEmojis {
+			clear(customEmojis.uris)
+		}
+		customEmojis.uris[sticker.CustomEmojiID] = resp.ContentURI.CUString()
+		customEmojis.mutex.Unlock()
+	}
+	return
+}
+
+// 把 spoiler 和自定义表情放进 formatted_body
+
+func (w *TelegramWorker) formatEntities(ctx context.Context, text string, entities []models.MessageEntity) (formatted string, ok bool) {
+	return misc.EntitiesToHTML(text, entities, w.customEmojiURIs(ctx, entities))
+}
@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot/models"
+	"maunium.net/go/mautrix/event"
+)
+
+/*
+转发的消息
+
+联系人转发的消息在 Matrix 上加一行来源，避免看起来像是联系人自己写的
+来源有公开的 username 的时候带上 t.me 的链接，频道的消息链接到原来的那条消息
+*/
+
+// 转发来源的名字和链接，不是转发的消息时 name 为空
+
+func forwardOrigin(msg *models.Message) (name, link string) {
+	origin := msg.ForwardOrigin
+	if origin == nil {
+		return
+	}
+
+	var signature *string
+	switch {
+	case origin.MessageOriginUser != nil:
+		user := origin.MessageOriginUser.SenderUser
+		name = misc.UserName(user.FirstName, user.LastName, user.Username, user.ID)
+		if user.Username != "" {
+			link = "https://t.me/" + user.Username
+		}
+	case origin.MessageOriginHiddenUser != nil:
+		name = origin.MessageOriginHiddenUser.SenderUserName
+	case origin.MessageOriginChat != nil:
+		chat := origin.MessageOriginChat.SenderChat
+		name = chat.Title
+		if chat.Username != "" {
+			link = "https://t.me/" + chat.Username
+		}
+		signature = origin.MessageOriginChat.AuthorSignature
+	case origin.MessageOriginChannel != nil:
+		chat := origin.MessageOriginChannel.Chat
+		name = chat.Title
+		if chat.Username != "" {
+			link = "https://t.me/" + chat.Username + "/" + strconv.Itoa(origin.MessageOriginChannel.MessageID)
+		}
+		signature = origin.MessageOriginChannel.AuthorSignature
+	}
+
+	if name == "" {
+		name = "unknown"
+	}
+	if signature != nil && *signature != "" {
+		name += " (" + *signature + ")"
+	}
+	return
+}
+
+// 转发来源的提示，分别是纯文本和 HTML，不是转发的消息时返回空字符串
+
+func forwardHeader(msg *models.Message) (plain, formatted string) {
+	name, link := forwardOrigin(msg)
+	if name == "" {
+		return
+	}
+
+	plain = "↪ Forwarded from " + name
+	formatted = "↪ Forwarded from " + html.EscapeString(name)
+	if link != "" {
+		plain += " (" + link + ")"
+		formatted = "↪ Forwarded from <a href=\"" + html.EscapeString(link) + "\">" + html.EscapeString(name) + "</a>"
+	}
+	return
+}
+
+// 在消息的 body 和 formatted_body 前面加上转发来源
+// 文件类的消息 body 会变成说明文字（MSC2530），所以要先把文件名放到 filename 里
+
+func addForwardHeader(msg *models.Message, content *event.MessageEventContent) {
+	plain, formatted := forwardHeader(msg)
+	if plain == "" {
+		return
+	}
+
+	if content.URL != "" && content.FileName == "" {
+		content.FileName = content.Body
+		content.Body = ""
+	}
+
+	formattedBody := content.FormattedBody
+	if content.Format != event.FormatHTML {
+		formattedBody = strings.ReplaceAll(html.EscapeString(content.Body), "\n", "<br>")
+	}
+	if content.Body == "" {
+		content.Body = plain
+		content.FormattedBody = formatted
+	} else {
+		content.Body = plain + "\n" + content.Body
+		content.FormattedBody = formatted + "<br>" + formattedBody
+	}
+	content.Format = event.FormatHTML
+}
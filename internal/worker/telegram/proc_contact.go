@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// Telegram 分享的联系人转发成 Matrix 上的 .vcf 文件，消息正文是可以直接阅读的名字和电话号码
+
+func (w *TelegramWorker) procContact(ctx context.Context, update *models.Update) (index []byte) {
+	index, info := w.getRoom(ctx, update)
+	if info == nil {
+		return
+	}
+
+	contact := update.Message.Contact
+	name := strings.TrimSpace(contact.FirstName + " " + contact.LastName)
+	vCard := contact.VCard
+	if vCard == "" {
+		vCard = misc.GenerateVCard(misc.VCardContact{
+			FirstName:   contact.FirstName,
+			LastName:    contact.LastName,
+			PhoneNumber: contact.PhoneNumber,
+		})
+	}
+
+	log.Info().
+		Str("User", getUserName(update)).
+		Str("Contact", name).
+		Msg("Contact from TG")
+
+	resp, err := w.Matrix.UploadBytes(ctx, []byte(vCard), "text/vcard")
+	if err != nil {
+		log.Err(err).Msg("Failed to upload vCard to Matrix")
+		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+		return
+	}
+
+	body := "Contact: " + name
+	if contact.PhoneNumber != "" {
+		body += ", " + contact.PhoneNumber
+	}
+	fileName := name
+	if fileName == "" {
+		fileName = "contact"
+	}
+
+	roomID := id.RoomID(info.GetRoomID())
+	content := &event.MessageEventContent{
+		MsgType:  event.MsgFile,
+		Body:     body,
+		FileName: fileName + ".vcf",
+		URL:      resp.ContentURI.CUString(),
+		Info: &event.FileInfo{
+			MimeType: "text/vcard",
+			Size:     len(vCard),
+		},
+	}
+	addForwardHeader(update.Message, content)
+	threadID, relatesTo := w.replyRelation(update.Message, roomID)
+	content.RelatesTo = relatesTo
+	sent, err := w.Matrix.SendMessageEvent(ctx, roomID, event.EventMessage, content)
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Matrix")
+		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, update.Message.Chat.ID, []int{update.Message.ID}, roomID, sent.EventID, threadID)
+	return
+}
@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+位置和地点
+
+Telegram 的 Location 和 Venue 转发成 Matrix 的 m.location，同时带上 MSC3488 的字段
+实时位置在 Telegram 上是不断被修改的同一条消息，每次收到 edited_message 的时候修改 Matrix 上的事件，直到共享结束
+*/
+
+func locationContent(msg *models.Message) *event.Content {
+	loc := msg.Location
+	if msg.Venue != nil {
+		loc = &msg.Venue.Location
+	}
+	geoURI := misc.GeoURI(loc.Latitude, loc.Longitude, loc.HorizontalAccuracy)
+
+	body := "Location: " + geoURI
+	location := map[string]any{"uri": geoURI}
+	// m.self 是自己的位置，m.pin 是地图上的一个点
+	asset := "m.self"
+	if msg.Venue != nil {
+		description := msg.Venue.Title
+		if msg.Venue.Address != "" {
+			description += ", " + msg.Venue.Address
+		}
+		body = "Venue: " + description + " (" + geoURI + ")"
+		location["description"] = description
+		asset = "m.pin"
+	} else if loc.LivePeriod != 0 {
+		body = "Live location: " + geoURI
+	}
+
+	parsed := &event.MessageEventContent{
+		MsgType: event.MsgLocation,
+		Body:    body,
+		GeoURI:  geoURI,
+	}
+	addForwardHeader(msg, parsed)
+	return &event.Content{
+		Parsed: parsed,
+		Raw: map[string]any{
+			"org.matrix.msc1767.text":     parsed.Body,
+			"org.matrix.msc3488.location": location,
+			"org.matrix.msc3488.asset":    map[string]any{"type": asset},
+			"org.matrix.msc3488.ts":       int64(msg.Date) * 1000,
+		},
+	}
+}
+
+func (w *TelegramWorker) procLocation(ctx context.Context, update *models.Update) (index []byte) {
+	index, info := w.getRoom(ctx, update)
+	if info == nil {
+		return
+	}
+
+	content := locationContent(update.Message)
+	log.Info().
+		Str("User", getUserName(update)).
+		Str("Location", content.Parsed.(*event.MessageEventContent).GeoURI).
+		Msg("Location from TG")
+
+	roomID := id.RoomID(info.GetRoomID())
+	threadID, relatesTo := w.replyRelation(update.Message, roomID)
+	content.Parsed.(*event.MessageEventContent).RelatesTo = relatesTo
+	resp, err := w.Matrix.SendMessageEvent(ctx, roomID, event.EventMessage, content)
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Matrix")
+		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, update.Message.Chat.ID, []int{update.Message.ID}, roomID, resp.EventID, threadID)
+	return
+}
+
+// 实时位置更新，修改之前发送到 Matrix 的事件
+
+func (w *TelegramWorker) procLiveLocation(ctx context.Context, update *models.Update) (index []byte) {
+	msg := update.EditedMessage
+
+	// 共享已经结束了
+	if msg.Location.LivePeriod == 0 || time.Now().After(time.Unix(int64(msg.Date)+int64(msg.Location.LivePeriod), 0)) {
+		log.Debug().Int64("ChatID", msg.Chat.ID).Int("MessageID", msg.ID).Msg("Live location ended, update ignored")
+		return
+	}
+
+	mapping, err := w.DataBase.MessageList.GetByTelegram(msg.Chat.ID, msg.ID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get message mapping")
+		return
+	}
+	if mapping == nil {
+		log.Debug().Int64("ChatID", msg.Chat.ID).Int("MessageID", msg.ID).Msg("Live location not bridged, update ignored")
+		return
+	}
+
+	// 房间已经换过了，或者联系人现在不应该转发消息
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByChatID(msg.Chat.ID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by ChatID")
+		return
+	}
+	if info == nil || info.GetRoomID() != mapping.GetRoomID() ||
+		info.GetLink() != types.RoomInfo_Linked || info.GetIgnored() ||
+		time.Now().Unix() < info.GetMutedUntil() {
+		return
+	}
+
+	content := locationContent(msg)
+	parsed := content.Parsed.(*event.MessageEventContent)
+	parsed.SetEdit(id.EventID(mapping.GetEventID()))
+	parsed.Body = "* " + parsed.Body
+	// MSC3488 的字段也要放进 m.new_content
+	content.Raw["m.new_content"] = maps.Clone(content.Raw)
+
+	log.Debug().
+		Int64("ChatID", msg.Chat.ID).
+		Str("Location", parsed.GeoURI).
+		Msg("Live location update from TG")
+
+	if _, err = w.Matrix.SendMessageEvent(ctx, id.RoomID(mapping.GetRoomID()), event.EventMessage, content); err != nil {
+		log.Err(err).Msg("Failed to update live location on Matrix")
+	}
+	return
+}
@@ -0,0 +1,294 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+图片和视频
+
+单独的图片和视频直接转发，说明文字放在同一个事件里（MSC2530）
+被标记为 spoiler 的图片和视频带上 MSC3725 的内容警告，支持的客户端会模糊显示
+相册里的每一张图片都是一个单独的 update，它们有相同的 media_group_id，说明文字只在其中一条消息上
+相册按照 ChatID 收集起来，AlbumWindow 毫秒内没有新的消息之后，按照顺序转发到 Matrix，最后转发说明文字
+同一个联系人的其他消息要等收集到的相册转发之后再转发，见 flushPendingAlbum
+*/
+
+// Bot API 最多只能下载 20 MB 的文件
+const maxDownloadSize = 20 << 20
+
+type album struct {
+	groupID string
+	updates []*models.Update
+	timer   *time.Timer
+	// 转发完之后关闭
+	done chan struct{}
+}
+
+type albumCollector struct {
+	mutex  sync.Mutex
+	albums map[int64]*album
+}
+
+var albums = albumCollector{albums: map[int64]*album{}}
+
+func (w *TelegramWorker) procMedia(ctx context.Context, update *models.Update) (index []byte) {
+	// 相册转发之后再更新房间信息
+	if update.Message.MediaGroupID != "" {
+		w.collectAlbum(update)
+		return
+	}
+
+	index, info := w.getRoom(ctx, update)
+	if info == nil {
+		return
+	}
+
+	log.Info().
+		Str("User", getUserName(update)).
+		Str("Caption", update.Message.Caption).
+		Msg("Media from TG")
+
+	roomID := id.RoomID(info.GetRoomID())
+	threadID, relatesTo := w.replyRelation(update.Message, roomID)
+	eventID, err := w.sendMedia(ctx, roomID, update, update.Message.Caption, relatesTo)
+	if err != nil {
+		log.Err(err).Msg("Failed to send media to Matrix")
+		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, update.Message.Chat.ID, []int{update.Message.ID}, roomID, eventID, threadID)
+	return
+}
+
+// 把相册里的一条消息放进收集器，每收到一条消息都重新计时
+// 同一个联系人开始发送另一个相册的时候，之前的相册马上转发
+
+func (w *TelegramWorker) collectAlbum(update *models.Update) {
+	chatID := update.Message.Chat.ID
+	window := time.Duration(w.Config.Get().Telegram.AlbumWindow) * time.Millisecond
+
+	albums.mutex.Lock()
+	defer albums.mutex.Unlock()
+
+	// Stop 返回 false 说明相册已经开始转发了，需要一个新的相册
+	if a := albums.albums[chatID]; a != nil && a.timer.Stop() {
+		if a.groupID == update.Message.MediaGroupID {
+			a.updates = append(a.updates, update)
+			a.timer.Reset(window)
+			return
+		}
+		go w.flushAlbum(chatID, a)
+	}
+
+	a := &album{
+		groupID: update.Message.MediaGroupID,
+		updates: []*models.Update{update},
+		done:    make(chan struct{}),
+	}
+	albums.albums[chatID] = a
+	w.WaitGroup.Add(1)
+	a.timer = time.AfterFunc(window, func() {
+		w.flushAlbum(chatID, a)
+	})
+}
+
+// 转发一个相册，转发完之前相册会留在收集器里，其他消息可以等它转发完
+
+func (w *TelegramWorker) flushAlbum(chatID int64, a *album) {
+	defer w.WaitGroup.Done()
+	defer func() {
+		albums.mutex.Lock()
+		if albums.albums[chatID] == a {
+			delete(albums.albums, chatID)
+		}
+		albums.mutex.Unlock()
+		close(a.done)
+	}()
+
+	// update 到达的顺序不一定是发送的顺序
+	slices.SortFunc(a.updates, func(a, b *models.Update) int {
+		return a.Message.ID - b.Message.ID
+	})
+	index := w.procAlbum(w.Context, a.updates)
+
+	// 更新房间信息
+	if err := misc.UpdateProfile(w.Context, w.Worker, index); err != nil {
+		log.Warn().Err(err).Msg("Failed to update profile")
+	}
+}
+
+// 联系人还有收集到的相册的时候马上转发，正在转发的时候等它转发完
+// 其他消息在这之后转发，避免 "相册，然后文字" 在 Matrix 上变成 "文字，然后相册"
+
+func (w *TelegramWorker) flushPendingAlbum(chatID int64) {
+	albums.mutex.Lock()
+	a := albums.albums[chatID]
+	if a == nil {
+		albums.mutex.Unlock()
+		return
+	}
+	if a.timer.Stop() {
+		albums.mutex.Unlock()
+		w.flushAlbum(chatID, a)
+		return
+	}
+	albums.mutex.Unlock()
+	<-a.done
+}
+
+func (w *TelegramWorker) procAlbum(ctx context.Context, updates []*models.Update) (index []byte) {
+	first := updates[0]
+	index, info := w.getRoom(ctx, first)
+	if info == nil {
+		return
+	}
+
+	log.Info().
+		Str("User", getUserName(first)).
+		Str("MediaGroupID", first.Message.MediaGroupID).
+		Int("Count", len(updates)).
+		Msg("Album from TG")
+
+	// 相册只有第一条消息带着回复，整个相册都放进同一个讨论串
+	roomID := id.RoomID(info.GetRoomID())
+	threadID, relatesTo := w.replyRelation(first.Message, roomID)
+	// 说明文字和格式拼在一起，格式的位置按照 UTF-16 计算
+	var captioned []int
+	var caption string
+	var entities []models.MessageEntity
+	for i, update := range updates {
+		rel := relatesTo
+		if i != 0 && threadID == "" {
+			rel = nil
+		}
+		eventID, err := w.sendMedia(ctx, roomID, update, "", rel)
+		if err != nil {
+			log.Err(err).Msg("Failed to send media to Matrix")
+			w.sendErrToTG(ctx, first.Message.Chat.ID, err)
+			return
+		}
+		misc.SaveThreadMessage(w.Worker, update.Message.Chat.ID, []int{update.Message.ID}, roomID, eventID, threadID)
+		if update.Message.Caption == "" {
+			continue
+		}
+		if caption != "" {
+			caption += "\n\n"
+		}
+		offset := misc.UTF16Len(caption)
+		for _, entity := range update.Message.CaptionEntities {
+			entity.Offset += offset
+			entities = append(entities, entity)
+		}
+		caption += update.Message.Caption
+		captioned = append(captioned, update.Message.ID)
+	}
+
+	if len(captioned) == 0 {
+		return
+	}
+	content := &event.MessageEventContent{
+		MsgType:   event.MsgText,
+		Body:      caption,
+		RelatesTo: relatesTo,
+	}
+	if formatted, ok := w.formatEntities(ctx, caption, entities); ok {
+		content.Format = event.FormatHTML
+		content.FormattedBody = formatted
+	}
+	addForwardHeader(first.Message, content)
+	resp, err := w.Matrix.SendMessageEvent(ctx, roomID, event.EventMessage, content)
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Matrix")
+		w.sendErrToTG(ctx, first.Message.Chat.ID, err)
+		return
+	}
+	// 回复带说明文字的消息的时候回复说明文字，已读回执也需要这个对应关系
+	misc.SaveThreadMessage(w.Worker, first.Message.Chat.ID, captioned, roomID, resp.EventID, threadID)
+	return
+}
+
+// 下载图片或者视频，上传到 Matrix 然后发送
+// 文件太大不能下载的时候，发送一条提示代替
+
+func (w *TelegramWorker) sendMedia(ctx context.Context, roomID id.RoomID, update *models.Update, caption string, relatesTo *event.RelatesTo) (eventID id.EventID, err error) {
+	msg := update.Message
+	var fileID string
+	var fileSize int64
+	content := &event.MessageEventContent{Info: &event.FileInfo{}, RelatesTo: relatesTo}
+	if msg.Video != nil {
+		fileID, fileSize = msg.Video.FileID, msg.Video.FileSize
+		content.MsgType = event.MsgVideo
+		content.Body = msg.Video.FileName
+		if content.Body == "" {
+			content.Body = "video.mp4"
+		}
+		content.Info.MimeType = msg.Video.MimeType
+		content.Info.Width = msg.Video.Width
+		content.Info.Height = msg.Video.Height
+		content.Info.Duration = msg.Video.Duration * 1000
+	} else {
+		// 最后一个是最大的尺寸
+		photo := msg.Photo[len(msg.Photo)-1]
+		fileID, fileSize = photo.FileID, int64(photo.FileSize)
+		content.MsgType = event.MsgImage
+		content.Body = "photo.jpg"
+		content.Info.MimeType = "image/jpeg"
+		content.Info.Width = photo.Width
+		content.Info.Height = photo.Height
+	}
+
+	if fileSize > maxDownloadSize {
+		log.Warn().Int64("Size", fileSize).Msg("File too big to download from Telegram")
+		var resp *mautrix.RespSendEvent
+		resp, err = w.Matrix.SendNotice(ctx, roomID, "sent a file that is too big to bridge (open in Telegram)")
+		if err != nil {
+			return
+		}
+		return resp.EventID, nil
+	}
+
+	data, err := misc.DownloadTelegramFile(ctx, w.Worker, fileID)
+	if err != nil {
+		return
+	}
+	if content.Info.MimeType == "" {
+		content.Info.MimeType = http.DetectContentType(data)
+	}
+	upload, err := w.Matrix.UploadBytes(ctx, data, content.Info.MimeType)
+	if err != nil {
+		return
+	}
+	content.URL = upload.ContentURI.CUString()
+	content.Info.Size = len(data)
+	if caption != "" {
+		content.FileName = content.Body
+		content.Body = caption
+		if formatted, ok := w.formatEntities(ctx, caption, msg.CaptionEntities); ok {
+			content.Format = event.FormatHTML
+			content.FormattedBody = formatted
+		}
+	}
+	addForwardHeader(msg, content)
+
+	full := &event.Content{Parsed: content}
+	if msg.HasMediaSpoiler {
+		full.Raw = map[string]any{misc.ContentWarningKey: map[string]any{"type": misc.ContentWarningSpoiler}}
+	}
+	resp, err := w.Matrix.SendMessageEvent(ctx, roomID, event.EventMessage, full)
+	if err != nil {
+		return
+	}
+	return resp.EventID, nil
+}
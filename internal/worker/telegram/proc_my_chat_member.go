@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+// 联系人屏蔽/解除屏蔽 Bot 的时候，Telegram 会发送 my_chat_member 更新
+// kicked 代表被屏蔽，member 代表解除屏蔽
+
+func (w *TelegramWorker) procMyChatMember(ctx context.Context, update *models.Update) (index []byte) {
+	member := update.MyChatMember
+	// 只处理私聊
+	if member.Chat.Type != models.ChatTypePrivate {
+		return
+	}
+
+	var status types.RoomInfo_ContactStatus
+	switch member.NewChatMember.Type {
+	case models.ChatMemberTypeBanned:
+		status = types.RoomInfo_Blocked
+	case models.ChatMemberTypeMember:
+		status = types.RoomInfo_Active
+	default:
+		return
+	}
+
+	index, info, err := w.DataBase.RoomList.GetRoomInfoByChatID(member.Chat.ID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by ChatID")
+		return
+	}
+	if info == nil {
+		log.Debug().
+			Int64("ChatID", member.Chat.ID).
+			Str("Status", string(member.NewChatMember.Type)).
+			Msg("Room not found")
+		return
+	}
+
+	if _, err = misc.SetContactStatus(ctx, w.Worker, index, status); err != nil {
+		log.Err(err).Msg("Failed to set contact status")
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+不能转发的内容
+
+骰子、游戏、动态、抽奖和置顶之类的服务消息，在 Matrix 里以 m.notice 的形式显示
+其他不认识的内容也会发送一条提示，被服务的用户总能知道有消息来过
+同时按照联系人的语言回复联系人，告诉联系人这条消息没有送达
+*/
+
+var diceVerbs = map[string]string{
+	"🎲": "rolled a %d",
+	"🎯": "threw a dart and scored %d",
+	"🏀": "shot a basketball and scored %d",
+	"⚽": "kicked a football and scored %d",
+	"🎳": "went bowling and scored %d",
+	"🎰": "spun the slot machine and got %d",
+}
+
+// 描述消息的内容，known 为 false 代表这是不认识的内容
+
+func describeMessage(msg *models.Message) (text string, known bool) {
+	switch {
+	case msg.Dice != nil:
+		verb, ok := diceVerbs[msg.Dice.Emoji]
+		if !ok {
+			verb = "sent a dice and got %d"
+		}
+		return msg.Dice.Emoji + " " + fmt.Sprintf(verb, msg.Dice.Value), true
+	case msg.Game != nil:
+		return "🎮 shared the game " + msg.Game.Title + " (open in Telegram)", true
+	case msg.Story != nil:
+		return "📖 shared a story (open in Telegram)", true
+	case msg.Giveaway != nil:
+		return fmt.Sprintf("🎁 started a giveaway with %d winner(s), winners are selected at %s (open in Telegram)",
+			msg.Giveaway.WinnerCount, time.Unix(int64(msg.Giveaway.WinnersSelectionDate), 0).Format(time.DateTime)), true
+	case msg.GiveawayCreated != nil:
+		return "🎁 created a giveaway (open in Telegram)", true
+	case msg.GiveawayWinners != nil:
+		return fmt.Sprintf("🎁 announced %d giveaway winner(s) (open in Telegram)", msg.GiveawayWinners.WinnerCount), true
+	case msg.GiveawayCompleted != nil:
+		return fmt.Sprintf("🎁 a giveaway has completed with %d winner(s)", msg.GiveawayCompleted.WinnerCount), true
+	case msg.PinnedMessage.Message != nil:
+		text = "📌 pinned a message"
+		if pinned := msg.PinnedMessage.Message.Text; pinned != "" {
+			text += ": " + pinned
+		}
+		return text, true
+	case msg.PinnedMessage.InaccessibleMessage != nil:
+		return "📌 pinned a message", true
+	case msg.MessageAutoDeleteTimerChanged != nil:
+		seconds := msg.MessageAutoDeleteTimerChanged.MessageAutoDeleteTime
+		if seconds == 0 {
+			return "⏲ turned off auto-delete", true
+		}
+		return "⏲ set messages to auto-delete after " + (time.Duration(seconds) * time.Second).String(), true
+	case msg.ChatBackgroundSet != nil:
+		return "🖼 changed the chat background", true
+	case msg.WriteAccessAllowed != nil:
+		return "✅ allowed the bot to send messages", true
+	case msg.UsersShared != nil:
+		return "👤 shared a user (open in Telegram)", true
+	case msg.ChatShared != nil:
+		return "💬 shared a chat (open in Telegram)", true
+	case msg.ProximityAlertTriggered != nil:
+		return fmt.Sprintf("📍 is within %d meters", msg.ProximityAlertTriggered.Distance), true
+	}
+
+	// 还不能转发的内容
+	kind := "a message"
+	switch {
+	case msg.Sticker != nil:
+		kind = "a sticker " + msg.Sticker.Emoji
+	case msg.Animation != nil:
+		kind = "a GIF"
+	case msg.VideoNote != nil:
+		kind = "a video message"
+	case msg.Voice != nil:
+		kind = "a voice message"
+	case msg.Audio != nil:
+		kind = "an audio file"
+	case msg.Document != nil:
+		kind = "a file"
+	case msg.PaidMedia != nil:
+		kind = "paid media"
+	case msg.Invoice != nil:
+		kind = "an invoice"
+	}
+	return "sent " + kind + " that can not be bridged (open in Telegram)", false
+}
+
+func (w *TelegramWorker) procNotice(ctx context.Context, update *models.Update) (index []byte) {
+	index, info := w.getRoom(ctx, update)
+	if info == nil {
+		return
+	}
+
+	text, known := describeMessage(update.Message)
+	log.Info().
+		Str("User", getUserName(update)).
+		Bool("Known", known).
+		Str("Notice", text).
+		Msg("Unbridgeable content from TG")
+
+	roomID := id.RoomID(info.GetRoomID())
+	content := &event.MessageEventContent{
+		MsgType: event.MsgNotice,
+		Body:    text,
+	}
+	// 文件、语音之类的内容带着的说明文字放在提示后面，联系人写的文字不会丢失
+	if caption := update.Message.Caption; caption != "" {
+		content.Body = text + "\n" + caption
+		if formatted, ok := w.formatEntities(ctx, caption, update.Message.CaptionEntities); ok {
+			content.Format = event.FormatHTML
+			content.FormattedBody = strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") + "<br>" + formatted
+		}
+	}
+	addForwardHeader(update.Message, content)
+	threadID, relatesTo := w.replyRelation(update.Message, roomID)
+	content.RelatesTo = relatesTo
+	resp, err := w.Matrix.SendMessageEvent(ctx, roomID, event.EventMessage, content)
+	if err != nil {
+		log.Err(err).Msg("Failed to send notice to Matrix")
+		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, update.Message.Chat.ID, []int{update.Message.ID}, roomID, resp.EventID, threadID)
+
+	if !known {
+		w.replyUnsupported(ctx, update.Message)
+	}
+	return
+}
+
+// 按照联系人的语言选择回复，先找完整的语言代码，再找主语言，最后使用 default
+
+func unsupportedReply(replies map[string]string, languageCode string) string {
+	languageCode = strings.ToLower(languageCode)
+	if reply, ok := replies[languageCode]; ok {
+		return reply
+	}
+	if language, _, found := strings.Cut(languageCode, "-"); found {
+		if reply, ok := replies[language]; ok {
+			return reply
+		}
+	}
+	return replies["default"]
+}
+
+func (w *TelegramWorker) replyUnsupported(ctx context.Context, msg *models.Message) {
+	var languageCode string
+	if msg.From != nil {
+		languageCode = msg.From.LanguageCode
+	}
+	reply := unsupportedReply(w.Config.Get().Telegram.UnsupportedReply, languageCode)
+	if reply == "" {
+		return
+	}
+
+	_, err := w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: msg.Chat.ID,
+		Text:   reply,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID:                msg.ID,
+			AllowSendingWithoutReply: true,
+		},
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to reply unsupported message")
+	}
+}
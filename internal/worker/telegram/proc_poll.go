@@ -0,0 +1,179 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+投票
+
+联系人发来的投票转发成 MSC3381 的 poll.start
+Bot 只能收到自己发出的投票的 poll_answer，也就是被服务的用户在 Matrix 上发起的投票，联系人的选择会转发成 poll.response
+投票结束的时候 Bot 会收到 poll 更新，转发成 poll.end
+*/
+
+func (w *TelegramWorker) procPoll(ctx context.Context, update *models.Update) (index []byte) {
+	index, info := w.getRoom(ctx, update)
+	if info == nil {
+		return
+	}
+	poll := update.Message.Poll
+
+	maxSelections := 1
+	if poll.AllowsMultipleAnswers {
+		maxSelections = len(poll.Options)
+	}
+	options := make([]string, 0, len(poll.Options))
+	answerIDs := make([]string, 0, len(poll.Options))
+	answers := make([]map[string]any, 0, len(poll.Options))
+	for i, option := range poll.Options {
+		answerID := strconv.Itoa(i)
+		options = append(options, option.Text)
+		answerIDs = append(answerIDs, answerID)
+		answers = append(answers, map[string]any{
+			"id":                      answerID,
+			"org.matrix.msc1767.text": option.Text,
+		})
+	}
+
+	log.Info().
+		Str("User", getUserName(update)).
+		Str("Question", poll.Question).
+		Msg("Poll from TG")
+
+	// 投票没有 body，转发来源放在纯文本的回退内容里
+	text := misc.PollText(poll.Question, options)
+	if header, _ := forwardHeader(update.Message); header != "" {
+		text = header + "\n" + text
+	}
+
+	roomID := id.RoomID(info.GetRoomID())
+	content := map[string]any{
+		"org.matrix.msc3381.poll.start": map[string]any{
+			"kind":           "org.matrix.msc3381.poll.disclosed",
+			"max_selections": maxSelections,
+			"question":       map[string]any{"org.matrix.msc1767.text": poll.Question},
+			"answers":        answers,
+		},
+		"org.matrix.msc1767.text": text,
+	}
+	threadID, relatesTo := w.replyRelation(update.Message, roomID)
+	if relatesTo != nil {
+		content["m.relates_to"] = relatesTo
+	}
+	resp, err := w.Matrix.SendMessageEvent(ctx, roomID, event.EventUnstablePollStart, content)
+	if err != nil {
+		log.Err(err).Msg("Failed to send poll to Matrix")
+		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, update.Message.Chat.ID, []int{update.Message.ID}, roomID, resp.EventID, threadID)
+
+	err = w.DataBase.PollList.Put(&types.PollInfo{
+		PollID:    poll.ID,
+		ChatID:    update.Message.Chat.ID,
+		MessageID: int64(update.Message.ID),
+		RoomID:    roomID.String(),
+		EventID:   resp.EventID.String(),
+		AnswerIDs: answerIDs,
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to save poll")
+	}
+	return
+}
+
+// 联系人在 Bot 发出的投票里投票
+
+func (w *TelegramWorker) procPollAnswer(ctx context.Context, update *models.Update) {
+	answer := update.PollAnswer
+	pollInfo, err := w.DataBase.PollList.GetByPollID(answer.PollID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get poll")
+		return
+	}
+	if pollInfo == nil || pollInfo.GetClosed() {
+		return
+	}
+	// 私聊里只有联系人自己可以投票
+	if answer.User == nil || answer.User.ID != pollInfo.GetChatID() {
+		return
+	}
+
+	content := &event.PollResponseEventContent{
+		RelatesTo: event.RelatesTo{
+			Type:    event.RelReference,
+			EventID: id.EventID(pollInfo.GetEventID()),
+		},
+	}
+	// 撤回投票的时候 OptionIDs 为空
+	content.Response.Answers = []string{}
+	for _, option := range answer.OptionIDs {
+		if option >= 0 && option < len(pollInfo.GetAnswerIDs()) {
+			content.Response.Answers = append(content.Response.Answers, pollInfo.GetAnswerIDs()[option])
+		}
+	}
+
+	log.Info().
+		Int64("ChatID", pollInfo.GetChatID()).
+		Strs("Answers", content.Response.Answers).
+		Msg("Poll answer from TG")
+
+	if _, err = w.Matrix.SendMessageEvent(ctx, id.RoomID(pollInfo.GetRoomID()), event.EventUnstablePollResponse, content); err != nil {
+		log.Err(err).Msg("Failed to send poll response to Matrix")
+	}
+}
+
+// 投票结束
+
+func (w *TelegramWorker) procPollUpdate(ctx context.Context, update *models.Update) {
+	poll := update.Poll
+	if !poll.IsClosed {
+		return
+	}
+	pollInfo, err := w.DataBase.PollList.GetByPollID(poll.ID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get poll")
+		return
+	}
+	if pollInfo == nil {
+		return
+	}
+	// 从 Matrix 结束的投票已经有 poll.end 了
+	closed, err := w.DataBase.PollList.Close(poll.ID)
+	if err != nil {
+		log.Err(err).Msg("Failed to close poll")
+		return
+	}
+	if !closed {
+		return
+	}
+
+	log.Info().
+		Int64("ChatID", pollInfo.GetChatID()).
+		Str("Question", poll.Question).
+		Msg("Poll closed on TG")
+
+	text := misc.PollResultText(poll)
+	_, err = w.Matrix.SendMessageEvent(ctx, id.RoomID(pollInfo.GetRoomID()), misc.EventUnstablePollEnd, map[string]any{
+		"m.relates_to": map[string]any{
+			"rel_type": event.RelReference,
+			"event_id": pollInfo.GetEventID(),
+		},
+		"org.matrix.msc3381.poll.end": map[string]any{},
+		"org.matrix.msc1767.text":     text,
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to send poll end to Matrix")
+	}
+}
@@ -5,14 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/AsenHu/mewlink/internal/types"
 	"github.com/AsenHu/mewlink/internal/worker/misc"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog/log"
-	"maunium.net/go/mautrix"
-	"maunium.net/go/mautrix/id"
 )
 
 /*
@@ -83,9 +82,19 @@ func (w *TelegramWorker) procStartMsg(ctx context.Context, update *models.Update
 			return
 		}
 
-		_, err = w.Telegram.SendMessage(ctx, &bot.SendMessageParams{
+		// 如果联系人之前屏蔽了 Bot，重新发送 /start 说明已经解除屏蔽了
+		text := "You have sent the start message before, please don't send it again"
+		changed, err := misc.SetContactStatus(ctx, w.Worker, index, types.RoomInfo_Active)
+		if err != nil {
+			log.Err(err).Msg("Failed to set contact status")
+		}
+		if changed {
+			text = "Welcome back! 🐾\nYour messages will be forwarded to Matrix friends again."
+		}
+
+		_, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
-			Text:   "You have sent the start message before, please don't send it again",
+			Text:   text,
 		})
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to send message to Telegram.")
@@ -102,7 +111,7 @@ func (w *TelegramWorker) procStartMsg(ctx context.Context, update *models.Update
 	w.WaitGroup.Add(1)
 	go func() {
 		defer w.WaitGroup.Done()
-		_, err := w.Telegram.SendMessage(ctx, &bot.SendMessageParams{
+		_, err := w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
 			Text:   "Welcome to MeowLink! 🐾\nFrom this message onwards, your message will be forwarded to Matrix friends.",
 		})
@@ -111,23 +120,19 @@ func (w *TelegramWorker) procStartMsg(ctx context.Context, update *models.Update
 		}
 	}()
 	// 创建房间
-	resp, err := w.Matrix.CreateRoom(ctx, &mautrix.ReqCreateRoom{
-		Name: username,
-		Invite: []id.UserID{
-			id.UserID(w.Config.Content.ServedUser),
-		},
-		IsDirect: true,
-		Preset:   "private_chat",
-	})
+	roomID, err := misc.CreateRoom(ctx, w.Worker, username, "")
 	if err != nil {
+		chatLock.(*sync.RWMutex).Unlock()
 		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
 		return
 	}
 	// 创建房间信息
 	info = &types.RoomInfo{
-		ChatID:   update.Message.Chat.ID,
-		RoomID:   string(resp.RoomID),
-		RoomName: username,
+		ChatID:    update.Message.Chat.ID,
+		RoomID:    string(roomID),
+		RoomName:  username,
+		Username:  update.Message.Chat.Username,
+		CreatedAt: time.Now().Unix(),
 	}
 
 	// 信息准备好了，准备锁
@@ -140,7 +145,7 @@ func (w *TelegramWorker) procStartMsg(ctx context.Context, update *models.Update
 	}
 	defer indexLock.Unlock()
 	// 锁定 RoomID
-	roomLock, _ := w.DataBase.RoomList.RoomIDMutex.LoadOrStore(string(resp.RoomID), &sync.RWMutex{})
+	roomLock, _ := w.DataBase.RoomList.RoomIDMutex.LoadOrStore(string(roomID), &sync.RWMutex{})
 	roomLock.(*sync.RWMutex).Lock()
 	defer roomLock.(*sync.RWMutex).Unlock()
 
@@ -156,7 +161,7 @@ func (w *TelegramWorker) procStartMsg(ctx context.Context, update *models.Update
 		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
 		return
 	}
-	if err = w.DataBase.RoomList.SetRoomIDIndex(resp.RoomID, index); err != nil {
+	if err = w.DataBase.RoomList.SetRoomIDIndex(roomID, index); err != nil {
 		log.Err(err).Msg("Failed to set RoomID index")
 		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
 		return
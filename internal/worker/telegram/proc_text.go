@@ -5,15 +5,56 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/AsenHu/mewlink/internal/types"
 	"github.com/AsenHu/mewlink/internal/worker/misc"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 )
 
 func (w *TelegramWorker) procText(ctx context.Context, update *models.Update) (index []byte) {
+	index, info := w.getRoom(ctx, update)
+	if info == nil {
+		return
+	}
+
+	log.Info().
+		Str("User", getUserName(update)).
+		Str("Msg", update.Message.Text).
+		Msg("Msg from TG")
+
+	// 转发消息到 Matrix
+	content := &event.MessageEventContent{
+		MsgType: event.MsgText,
+		Body:    update.Message.Text,
+	}
+	if formatted, ok := w.formatEntities(ctx, update.Message.Text, update.Message.Entities); ok {
+		content.Format = event.FormatHTML
+		content.FormattedBody = formatted
+	}
+	addForwardHeader(update.Message, content)
+	roomID := id.RoomID(info.GetRoomID())
+	threadID, relatesTo := w.replyRelation(update.Message, roomID)
+	content.RelatesTo = relatesTo
+	resp, err := w.Matrix.SendMessageEvent(ctx, roomID, event.EventMessage, content)
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Matrix")
+		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+		return
+	}
+	misc.SaveThreadMessage(w.Worker, update.Message.Chat.ID, []int{update.Message.ID}, roomID, resp.EventID, threadID)
+
+	return
+}
+
+// 获取联系人的房间，房间需要的时候会重新创建
+// 消息不应该转发的时候 info 为 nil，需要的提示已经发送过了
+
+func (w *TelegramWorker) getRoom(ctx context.Context, update *models.Update) (index []byte, info *types.RoomInfo) {
 	username := getUserName(update)
 
 	// 获取房间信息的 index
@@ -26,30 +67,30 @@ func (w *TelegramWorker) procText(ctx context.Context, update *models.Update) (i
 	if err != nil {
 		log.Err(err).Msg("Failed to get index by ChatID")
 		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
-		return
+		return index, nil
 	}
 	// 检查房间是否存在
 	if index == nil {
 		log.Warn().Int64("ChatID", update.Message.Chat.ID).Str("User", username).Msg("Room not found")
-		_, err = w.Telegram.SendMessage(ctx, &bot.SendMessageParams{
+		_, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
 			Text:   "Please resend `/start`",
 		})
 		if err != nil {
 			log.Err(err).Msg("Failed to send message to Telegram")
 		}
-		return
+		return index, nil
 	}
 	// 获取房间信息
 	// 这里的锁同上
 	indexLock, _ := w.DataBase.RoomList.RoomInfoBucket.IndexMutex.LoadOrStore(string(index), &sync.RWMutex{})
 	indexLock.(*sync.RWMutex).RLock()
-	info, err := w.DataBase.RoomList.GetRoomInfoByIndex(index)
+	info, err = w.DataBase.RoomList.GetRoomInfoByIndex(index)
 	indexLock.(*sync.RWMutex).RUnlock()
 	if err != nil {
 		log.Err(err).Msg("Failed to get RoomInfo by index")
 		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
-		return
+		return index, nil
 	}
 	// 检查房间信息是否合法
 	if !misc.IsGoodRoomInfo(info) {
@@ -63,20 +104,65 @@ func (w *TelegramWorker) procText(ctx context.Context, update *models.Update) (i
 			Msg("RoomInfo not valid, this should not happen, database corrupted")
 		w.StopProc()
 		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
-		return
+		return index, nil
 	}
 
-	log.Info().
-		Str("User", username).
-		Str("Msg", update.Message.Text).
-		Msg("Msg from TG")
+	// 被服务的用户屏蔽了这个联系人
+	if info.GetIgnored() {
+		log.Info().
+			Int64("ChatID", update.Message.Chat.ID).
+			Str("User", username).
+			Msg("Contact ignored, message dropped")
+		return index, nil
+	}
 
-	// 转发消息到 Matrix
-	_, err = w.Matrix.SendText(ctx, id.RoomID(info.GetRoomID()), update.Message.Text)
+	// 联系人因为刷屏被禁言了
+	if time.Now().Unix() < info.GetMutedUntil() {
+		log.Info().
+			Int64("ChatID", update.Message.Chat.ID).
+			Str("User", username).
+			Msg("Contact muted, message dropped")
+		return index, nil
+	}
+
+	// 能收到消息说明联系人没有屏蔽 Bot
+	if info.GetStatus() != types.RoomInfo_Active {
+		if _, err = misc.SetContactStatus(ctx, w.Worker, index, types.RoomInfo_Active); err != nil {
+			log.Err(err).Msg("Failed to set contact status")
+		}
+	}
+
+	// 被服务的用户离开了房间
+	switch info.GetLink() {
+	case types.RoomInfo_Dormant:
+		// 重新创建一个房间
+		info, err = misc.ReopenRoom(ctx, w.Worker, index)
+		if err != nil {
+			log.Err(err).Msg("Failed to reopen room")
+			w.sendErrToTG(ctx, update.Message.Chat.ID, err)
+			return index, nil
+		}
+	case types.RoomInfo_Archived:
+		_, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "This chat has been archived, your message was not delivered",
+		})
+		if err != nil {
+			log.Err(err).Msg("Failed to send message to Telegram")
+		}
+		return index, nil
+	}
+
+	// 记录联系人最后一条消息，被服务的用户已读的时候标记这条消息
+	_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+		if int64(update.Message.ID) <= info.GetLastMessageID() {
+			return false
+		}
+		info.LastMessageID = int64(update.Message.ID)
+		return true
+	})
 	if err != nil {
-		log.Err(err).Msg("Failed to send message to Matrix")
-		w.sendErrToTG(ctx, update.Message.Chat.ID, err)
-		return
+		log.Warn().Err(err).Msg("Failed to save last message")
 	}
 
 	return
@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AsenHu/mewlink/internal/types"
+	"github.com/AsenHu/mewlink/internal/worker/misc"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/id"
+)
+
+/*
+联系人限流
+
+每个联系人有一个令牌桶，每条消息消耗一个令牌，令牌按照 RateLimit 每分钟补充
+令牌用完之后的消息会被丢弃，第一次超限的时候警告联系人，恢复之后在 Matrix 房间里汇总被丢弃的消息数量
+在 floodWindow 内超限 MuteAfter 次的联系人会被禁言 MuteDuration 秒，禁言状态保存在 RoomInfo 里
+
+限流只在内存里检查，在创建 goroutine 之前完成，刷屏的联系人不会创建大量的 goroutine
+令牌桶保存在 misc 里，Matrix 那边解除禁言的时候也要重置
+*/
+
+func (w TelegramWorker) checkRateLimit(update *models.Update) (result misc.LimitResult, dropped int) {
+	if update.Message == nil || update.Message.Chat.Type != models.ChatTypePrivate {
+		return
+	}
+	cfg := w.Config.Get().Telegram
+	if cfg.RateLimit == 0 {
+		return
+	}
+	return misc.TakeRateLimit(update.Message.Chat.ID, cfg.RateLimit, max(cfg.RateLimitBurst, 1),
+		cfg.MuteAfter, time.Duration(cfg.MuteDuration)*time.Second)
+}
+
+// 在联系人的房间里发送提示，联系人没有可用的房间时不发送
+
+func (w TelegramWorker) noticeContactRoom(ctx context.Context, chatID int64, text string) {
+	_, info, err := w.DataBase.RoomList.GetRoomInfoByChatID(chatID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by ChatID")
+		return
+	}
+	if info == nil || info.GetLink() != types.RoomInfo_Linked {
+		return
+	}
+	if _, err = w.Matrix.SendNotice(ctx, id.RoomID(info.GetRoomID()), text); err != nil {
+		log.Warn().Err(err).Msg("Failed to send notice to Matrix")
+	}
+}
+
+func (w TelegramWorker) warnFlood(ctx context.Context, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	log.Warn().
+		Int64("ChatID", chatID).
+		Str("User", getUserName(update)).
+		Msg("Contact is flooding, messages dropped")
+
+	_, err := w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   "You are sending messages too fast, some of them were not delivered. Please slow down 🐾",
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Telegram")
+	}
+}
+
+func (w TelegramWorker) muteContact(ctx context.Context, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	duration := time.Duration(w.Config.Get().Telegram.MuteDuration) * time.Second
+	until := time.Now().Add(duration)
+	log.Warn().
+		Int64("ChatID", chatID).
+		Str("User", getUserName(update)).
+		Time("Until", until).
+		Msg("Contact muted for flooding")
+
+	// 保存禁言状态，重启之后也会继续生效
+	index, _, err := w.DataBase.RoomList.GetRoomInfoByChatID(chatID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get RoomInfo by ChatID")
+	} else if index != nil {
+		_, err = w.DataBase.RoomList.UpdateRoomInfoByIndex(index, func(info *types.RoomInfo) bool {
+			info.MutedUntil = until.Unix()
+			return true
+		})
+		if err != nil {
+			log.Err(err).Msg("Failed to update RoomInfo")
+		}
+	}
+
+	w.noticeContactRoom(ctx, chatID, fmt.Sprintf("This contact kept flooding and is muted until %s, use `!mewlink unmute` to unmute",
+		until.Format(time.DateTime)))
+	misc.Alert(ctx, w.Worker, fmt.Sprintf("%s (ChatID %d) is muted until %s for flooding",
+		getUserName(update), chatID, until.Format(time.DateTime)))
+
+	_, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   fmt.Sprintf("You are sending messages too fast, your messages will not be delivered for %s", duration),
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to send message to Telegram")
+	}
+}
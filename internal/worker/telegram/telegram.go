@@ -3,7 +3,7 @@ package telegram
 import (
 	"context"
 	"encoding/json"
-	"strconv"
+	"fmt"
 
 	"github.com/AsenHu/mewlink/internal/worker"
 	"github.com/AsenHu/mewlink/internal/worker/misc"
@@ -18,63 +18,133 @@ type TelegramWorker struct {
 }
 
 func (w TelegramWorker) FromTelegram(_ context.Context, _ *bot.Bot, update *models.Update) {
+	// 限流要在创建 goroutine 之前检查
+	// 被屏蔽的用户不限流，消息会在 procUpdate 里丢弃，不会收到限流的警告
+	var result misc.LimitResult
+	var dropped int
+	if update.Message != nil && w.isAllowed(update.Message.Chat) {
+		result, dropped = w.checkRateLimit(update)
+	}
+	if result == misc.LimitDrop || result == misc.LimitMuted {
+		return
+	}
+
 	w.WaitGroup.Add(1)
 	go func() {
 		defer w.WaitGroup.Done()
-		// 确定消息类型，然后调用相应的处理函数
-
-		// 1. 如果是 `/start`，调用 `procStartMsg`
-		// 2. 如果是普通消息，调用 `procText`
-		// 3. 如果是其他消息，直接返回
-
-		var index []byte
-		switch {
-		case update.Message.Text == "/start":
-			index = w.procStartMsg(w.Context, update)
-		case update.Message.Text != "":
-			index = w.procText(w.Context, update)
+		switch result {
+		case misc.LimitFlood, misc.LimitMute:
+			// 等待审批或者被拒绝的联系人只丢弃消息，不警告联系人，也不提醒管理房间
+			needs, err := w.needsApproval(update.Message.Chat)
+			if err != nil {
+				log.Err(err).Msg("Failed to get index by ChatID")
+				return
+			}
+			if needs {
+				return
+			}
+			if result == misc.LimitFlood {
+				w.warnFlood(w.Context, update)
+			} else {
+				w.muteContact(w.Context, update)
+			}
 		default:
-			if w.Config.Content.LogLevel == zerolog.DebugLevel {
-				jsonUpdate, _ := json.Marshal(update)
-				log.Debug().
-					Str("Update", string(jsonUpdate)).
-					Msg("Unsupported message type")
+			if dropped != 0 {
+				w.noticeContactRoom(w.Context, update.Message.Chat.ID,
+					fmt.Sprintf("%d message(s) from this contact were dropped by the rate limit", dropped))
 			}
-			return
-		}
-
-		// 杂项操作
-		// 更新房间信息
-		if err := misc.UpdateProfile(w.Context, index, w.Matrix); err != nil {
-			log.Warn().Err(err).Msg("Failed to update profile")
+			w.procUpdate(w.Context, update)
 		}
 	}()
 }
 
-func getUserName(update *models.Update) string {
-	// 1. 尝试拼接 FirstName 和 LastName
-	if update.Message.Chat.FirstName != "" || update.Message.Chat.LastName != "" {
-		if update.Message.Chat.FirstName == "" {
-			return update.Message.Chat.LastName
-		}
-		if update.Message.Chat.LastName == "" {
-			return update.Message.Chat.FirstName
+func (w TelegramWorker) procUpdate(ctx context.Context, update *models.Update) {
+	// 屏蔽列表里的用户，或者开启了只允许列表时不在允许列表里的用户，消息直接丢弃
+	// 编辑的消息、投票和 Bot 状态变化也一样
+	if !w.checkAccess(ctx, update) {
+		return
+	}
+	// 审批模式下，新的联系人需要先通过审批
+	if update.Message != nil && !w.checkApproval(ctx, update) {
+		return
+	}
+	w.ProcApproved(ctx, update)
+}
+
+// 处理已经通过检查的 update，审批通过之后转发缓存的消息也会调用这里
+
+func (w TelegramWorker) ProcApproved(ctx context.Context, update *models.Update) (index []byte) {
+	// 之前的相册还在等待其他图片的时候，先把相册转发出去
+	if update.Message != nil && update.Message.MediaGroupID == "" {
+		w.flushPendingAlbum(update.Message.Chat.ID)
+	}
+
+	// 确定消息类型，然后调用相应的处理函数
+
+	// 1. 如果是 Bot 状态变化（被屏蔽等），调用 `procMyChatMember`
+	// 2. 如果是 `/start`，调用 `procStartMsg`
+	// 3. 如果是普通消息，调用 `procText`
+	// 4. 如果是位置或者地点，调用 `procLocation`
+	// 5. 如果是联系人，调用 `procContact`
+	// 6. 如果是投票，调用 `procPoll`
+	// 7. 如果是图片或者视频，调用 `procMedia`，相册会被收集起来一起转发
+	// 8. 如果是实时位置的更新，调用 `procLiveLocation`
+	// 9. 如果是投票的选择或者投票结束，调用 `procPollAnswer` 或 `procPollUpdate`
+	// 10. 如果是其他消息，调用 `procNotice` 在 Matrix 里发送提示
+	// 11. 如果是其他更新，直接返回
+
+	switch {
+	case update.MyChatMember != nil:
+		index = w.procMyChatMember(ctx, update)
+	case update.Message != nil && update.Message.Text == "/start":
+		index = w.procStartMsg(ctx, update)
+	case update.Message != nil && update.Message.Text != "":
+		index = w.procText(ctx, update)
+	case update.Message != nil && (update.Message.Location != nil || update.Message.Venue != nil):
+		index = w.procLocation(ctx, update)
+	case update.Message != nil && update.Message.Contact != nil:
+		index = w.procContact(ctx, update)
+	case update.Message != nil && update.Message.Poll != nil:
+		index = w.procPoll(ctx, update)
+	case update.Message != nil && (update.Message.Photo != nil || update.Message.Video != nil):
+		index = w.procMedia(ctx, update)
+	case update.EditedMessage != nil && update.EditedMessage.Location != nil:
+		index = w.procLiveLocation(ctx, update)
+	case update.PollAnswer != nil:
+		w.procPollAnswer(ctx, update)
+	case update.Poll != nil:
+		w.procPollUpdate(ctx, update)
+	case update.Message != nil:
+		index = w.procNotice(ctx, update)
+	default:
+		if w.Config.Get().LogLevel == zerolog.DebugLevel {
+			jsonUpdate, _ := json.Marshal(update)
+			log.Debug().
+				Str("Update", string(jsonUpdate)).
+				Msg("Unsupported message type")
 		}
-		return update.Message.Chat.FirstName + " " + update.Message.Chat.LastName
+		return
 	}
 
-	// 2. 如果拼接失败，使用 Username
-	if update.Message.Chat.Username != "" {
-		return update.Message.Chat.Username
+	// 杂项操作
+	// 更新房间信息
+	if err := misc.UpdateProfile(ctx, w.Worker, index); err != nil {
+		log.Warn().Err(err).Msg("Failed to update profile")
 	}
+	return
+}
 
-	// 3. 如果 Username 为空，使用 UserID
-	return strconv.FormatInt(update.Message.Chat.ID, 10)
+func getUserName(update *models.Update) string {
+	chat := update.Message.Chat
+	return misc.UserName(chat.FirstName, chat.LastName, chat.Username, chat.ID)
 }
 
 func (w *TelegramWorker) sendErrToTG(ctx context.Context, chatID int64, err error) {
+	// 联系人那边看到的错误，被服务的用户是看不到的，所以也发到管理房间
+	misc.Alert(ctx, w.Worker, fmt.Sprintf("Error while bridging messages from Telegram ChatID %d: %s", chatID, err))
+
 	message := err.Error() + "\nAn error occurred on the Matrix side. Please try to contact the user through other means."
-	_, err = w.Telegram.SendMessage(ctx, &bot.SendMessageParams{
+	_, err = w.Outbox.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: chatID,
 		Text:   message,
 	})
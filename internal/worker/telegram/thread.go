@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// 联系人回复的消息在讨论串里的时候，消息转发到同一个讨论串里，否则作为普通的回复
+// 回复的消息没有转发过的时候 relatesTo 为 nil
+
+func (w *TelegramWorker) replyRelation(msg *models.Message, roomID id.RoomID) (threadID id.EventID, relatesTo *event.RelatesTo) {
+	if msg.ReplyToMessage == nil {
+		return
+	}
+	mapping, err := w.DataBase.MessageList.GetByTelegram(msg.Chat.ID, msg.ReplyToMessage.ID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get message mapping")
+		return
+	}
+	if mapping == nil || mapping.GetRoomID() != roomID.String() {
+		return
+	}
+
+	replyTo := id.EventID(mapping.GetEventID())
+	threadID = id.EventID(mapping.GetThreadID())
+	if threadID == "" {
+		return "", (&event.RelatesTo{}).SetReplyTo(replyTo)
+	}
+	// 回复的是讨论串里的一条消息，不是回退用的 m.in_reply_to
+	relatesTo = (&event.RelatesTo{}).SetThread(threadID, replyTo)
+	relatesTo.IsFallingBack = false
+	return
+}
@@ -6,16 +6,25 @@ import (
 
 	"github.com/AsenHu/mewlink/internal/config"
 	"github.com/AsenHu/mewlink/internal/database"
+	"github.com/AsenHu/mewlink/internal/outbox"
 	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
 	"maunium.net/go/mautrix"
 )
 
 type Worker struct {
-	Matrix    *mautrix.Client
-	Telegram  *bot.Bot
+	Matrix   *mautrix.Client
+	Telegram *bot.Bot
+	// 写入 Telegram 的请求都要经过 Outbox，不要直接调用 Telegram 的发送方法
+	Outbox    *outbox.Outbox
 	DataBase  *database.DataBase
 	Config    *config.Config
 	WaitGroup *sync.WaitGroup
 	Context   context.Context
-	StopProc  context.CancelFunc
+	// 停止同步的时候取消，之后不会再收到新的事件
+	SyncContext context.Context
+	StopProc    context.CancelFunc
+	// 处理审批通过的联系人的 update，不再检查审批，返回联系人的 index
+	// 两边的 Worker 不能互相引用，所以由 Telegram Worker 在启动的时候设置
+	ProcApproved func(ctx context.Context, update *models.Update) (index []byte)
 }
@@ -1,4 +1,4 @@
 package mewlink
 
 //go:generate go install -v google.golang.org/protobuf/cmd/protoc-gen-go@latest
-//go:generate protoc --go_out=. --go_opt=paths=import --go_opt=module=github.com/AsenHu/mewlink ./protos/roominfo.proto
+//go:generate protoc --go_out=. --go_opt=paths=import --go_opt=module=github.com/AsenHu/mewlink ./protos/roominfo.proto ./protos/pendinginfo.proto ./protos/messageinfo.proto ./protos/pollinfo.proto